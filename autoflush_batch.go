@@ -0,0 +1,160 @@
+package db
+
+// AutoFlushBatch is a Batch which transparently writes its underlying batch and starts a new one
+// whenever it would exceed a size or operation count threshold. This allows loading large amounts
+// of data in bounded chunks, e.g. to stay within Badger's transaction size limits.
+//
+// Since it writes the data in several sub-batches, the batch as a whole is not atomic: sub-batches
+// which have already been written remain written even if the batch is closed without calling
+// Write. Len and GetByteSize report on the current, unwritten sub-batch.
+type AutoFlushBatch struct {
+	db       DB
+	batch    Batch
+	maxBytes int
+	maxOps   int
+	flushes  int
+}
+
+var _ Batch = (*AutoFlushBatch)(nil)
+
+// NewAutoFlushBatch creates a new auto-flushing batch on the given database. A sub-batch is
+// written when adding an operation would make it larger than maxBytes, or contain more than maxOps
+// operations. A single operation larger than maxBytes is written in a sub-batch of its own.
+// Thresholds less than or equal to 0 are ignored.
+func NewAutoFlushBatch(db DB, maxBytes, maxOps int) *AutoFlushBatch {
+	return &AutoFlushBatch{
+		db:       db,
+		batch:    db.NewBatch(),
+		maxBytes: maxBytes,
+		maxOps:   maxOps,
+	}
+}
+
+// Set implements Batch.
+func (b *AutoFlushBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if err := b.reserve(len(key) + len(value)); err != nil {
+		return err
+	}
+	return b.batch.Set(key, value)
+}
+
+// Delete implements Batch.
+func (b *AutoFlushBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if err := b.reserve(len(key)); err != nil {
+		return err
+	}
+	return b.batch.Delete(key)
+}
+
+// DeleteRange implements Batch. On backends without native range deletion, the range is added to
+// the current sub-batch as individual deletions, which may take it past the thresholds.
+func (b *AutoFlushBatch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if err := b.reserve(len(start) + len(end)); err != nil {
+		return err
+	}
+	return b.batch.DeleteRange(start, end)
+}
+
+// reserve writes the current sub-batch and starts a new one if adding an operation of the given
+// size would take it past the thresholds.
+func (b *AutoFlushBatch) reserve(size int) error {
+	if b.batch == nil {
+		return errBatchClosed
+	}
+	count := b.batch.Len()
+	if count == 0 {
+		return nil
+	}
+	current, err := b.batch.GetByteSize()
+	if err != nil {
+		return err
+	}
+	if (b.maxOps <= 0 || count+1 <= b.maxOps) && (b.maxBytes <= 0 || current+size <= b.maxBytes) {
+		return nil
+	}
+	if err = b.batch.Write(); err != nil {
+		return err
+	}
+	b.flushes++
+	if err = b.batch.Close(); err != nil {
+		return err
+	}
+	b.batch = b.db.NewBatch()
+	return nil
+}
+
+// GetByteSize implements Batch.
+func (b *AutoFlushBatch) GetByteSize() (int, error) {
+	if b.batch == nil {
+		return 0, errBatchClosed
+	}
+	return b.batch.GetByteSize()
+}
+
+// Len implements Batch.
+func (b *AutoFlushBatch) Len() int {
+	if b.batch == nil {
+		return 0
+	}
+	return b.batch.Len()
+}
+
+// Flushes returns the number of sub-batches written so far, including the final one written by
+// Write or WriteSync if it was not empty.
+func (b *AutoFlushBatch) Flushes() int {
+	return b.flushes
+}
+
+// Write implements Batch.
+func (b *AutoFlushBatch) Write() error {
+	return b.write(false)
+}
+
+// WriteSync implements Batch. Only the final sub-batch is written synchronously, which on most
+// backends also flushes the previously written ones to disk.
+func (b *AutoFlushBatch) WriteSync() error {
+	return b.write(true)
+}
+
+func (b *AutoFlushBatch) write(sync bool) error {
+	if b.batch == nil {
+		return errBatchClosed
+	}
+	pending := b.batch.Len() > 0
+	var err error
+	if sync {
+		err = b.batch.WriteSync()
+	} else {
+		err = b.batch.Write()
+	}
+	if err != nil {
+		return err
+	}
+	if pending {
+		b.flushes++
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// Close implements Batch.
+func (b *AutoFlushBatch) Close() error {
+	if b.batch == nil {
+		return nil
+	}
+	err := b.batch.Close()
+	b.batch = nil
+	return err
+}
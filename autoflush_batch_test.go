@@ -0,0 +1,70 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoFlushBatchMaxOps(t *testing.T) {
+	db := NewMemDB()
+	batch := NewAutoFlushBatch(db, 0, 3)
+	defer batch.Close()
+
+	for i := int64(0); i < 10; i++ {
+		require.NoError(t, batch.Set(int642Bytes(i), []byte{1}))
+	}
+	require.Equal(t, 3, batch.Flushes())
+	require.Equal(t, 1, batch.Len())
+
+	// the sub-batches written so far should be visible, but not the pending one.
+	ok, err := db.Has(int642Bytes(8))
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = db.Has(int642Bytes(9))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, batch.Write())
+	require.Equal(t, 4, batch.Flushes())
+	require.Equal(t, errBatchClosed, batch.Set([]byte("a"), []byte{1}))
+	require.NoError(t, batch.Close())
+
+	ok, err = db.Has(int642Bytes(9))
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestAutoFlushBatchMaxBytes(t *testing.T) {
+	db := NewMemDB()
+	batch := NewAutoFlushBatch(db, 10, 0)
+	defer batch.Close()
+
+	require.NoError(t, batch.Set([]byte("a"), []byte("1234")))
+	require.NoError(t, batch.Set([]byte("b"), []byte("1234")))
+	require.Zero(t, batch.Flushes())
+	size, err := batch.GetByteSize()
+	require.NoError(t, err)
+	require.Equal(t, 10, size)
+
+	// an operation that doesn't fit should flush the pending ones first, even if it is too large
+	// to fit in a sub-batch on its own.
+	require.NoError(t, batch.Set([]byte("c"), []byte("123456789012")))
+	require.Equal(t, 1, batch.Flushes())
+	require.Equal(t, 1, batch.Len())
+	require.NoError(t, batch.Delete([]byte("a")))
+	require.Equal(t, 2, batch.Flushes())
+
+	// closing the batch should discard the pending operations, but not the written ones.
+	require.NoError(t, batch.Close())
+	assertKeyValues(t, db, map[string][]byte{
+		"a": []byte("1234"),
+		"b": []byte("1234"),
+		"c": []byte("123456789012"),
+	})
+
+	// writing an empty batch does not count as a flush.
+	batch = NewAutoFlushBatch(db, 10, 10)
+	require.NoError(t, batch.WriteSync())
+	require.Zero(t, batch.Flushes())
+}
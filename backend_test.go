@@ -1,10 +1,13 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,7 +17,7 @@ import (
 // Register a test backend for PrefixDB as well, with some unrelated junk data
 func init() {
 	// nolint: errcheck
-	registerDBCreator("prefixdb", func(name, dir string) (DB, error) {
+	registerDBCreator("prefixdb", func(name, dir string, opts Options) (DB, error) {
 		mdb := NewMemDB()
 		mdb.Set([]byte("a"), []byte{1})
 		mdb.Set([]byte("b"), []byte{2})
@@ -420,3 +423,977 @@ func assertKeyValues(t *testing.T, db DB, expect map[string][]byte) {
 
 	assert.Equal(t, expect, actual)
 }
+
+func TestDBSnapshot(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBSnapshot(t, dbType)
+		})
+	}
+}
+
+func testDBSnapshot(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+	require.NoError(t, db.Set([]byte("c"), []byte{3}))
+
+	snapshot, err := db.Snapshot()
+	require.NoError(t, err)
+
+	// writes made after the snapshot was taken should not be visible in it. Bolt blocks writers
+	// that need to grow its memory map while a read transaction is open, so we write from a
+	// separate goroutine and only wait for it once the snapshot is closed.
+	writeErr := make(chan error, 1)
+	go func() {
+		var err error
+		for _, op := range []func() error{
+			func() error { return db.Set([]byte("a"), []byte{9}) },
+			func() error { return db.Delete([]byte("b")) },
+			func() error { return db.Set([]byte("d"), []byte{4}) },
+		} {
+			if err = op(); err != nil {
+				break
+			}
+		}
+		writeErr <- err
+	}()
+	if backend != BoltDBBackend {
+		require.NoError(t, <-writeErr)
+		assertKeyValues(t, db, map[string][]byte{"a": {9}, "c": {3}, "d": {4}})
+	}
+
+	value, err := snapshot.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, value)
+
+	value, err = snapshot.Get([]byte("d"))
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	ok, err := snapshot.Has([]byte("b"))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = snapshot.Has([]byte("d"))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = snapshot.Get(nil)
+	require.Equal(t, errKeyEmpty, err)
+	_, err = snapshot.Has([]byte{})
+	require.Equal(t, errKeyEmpty, err)
+	_, err = snapshot.Iterator([]byte{}, nil)
+	require.Equal(t, errKeyEmpty, err)
+	_, err = snapshot.ReverseIterator(nil, []byte{})
+	require.Equal(t, errKeyEmpty, err)
+
+	itr, err := snapshot.Iterator(nil, nil)
+	require.NoError(t, err)
+	var keys []string
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, string(itr.Key()))
+	}
+	require.NoError(t, itr.Error())
+	require.NoError(t, itr.Close())
+	require.Equal(t, []string{"a", "b", "c"}, keys)
+
+	ritr, err := snapshot.ReverseIterator([]byte("a"), []byte("c"))
+	require.NoError(t, err)
+	keys = nil
+	for ; ritr.Valid(); ritr.Next() {
+		keys = append(keys, string(ritr.Key()))
+	}
+	require.NoError(t, ritr.Error())
+	require.NoError(t, ritr.Close())
+	require.Equal(t, []string{"b", "a"}, keys)
+
+	require.NoError(t, snapshot.Close())
+	if backend == BoltDBBackend {
+		require.NoError(t, <-writeErr)
+	}
+	assertKeyValues(t, db, map[string][]byte{"a": {9}, "c": {3}, "d": {4}})
+}
+
+func TestDBIteratorWrites(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBIteratorWrites(t, dbType)
+		})
+	}
+}
+
+func testDBIteratorWrites(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+
+	expect := make([]int64, 0, 100)
+	for i := int64(0); i < 100; i++ {
+		require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+		expect = append(expect, i)
+	}
+
+	// rewriting keys while iterating over them, from the same goroutine, should neither deadlock
+	// nor affect the iterator
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	var keys []int64
+	for ; itr.Valid(); itr.Next() {
+		require.Equal(t, itr.Key(), itr.Value())
+		keys = append(keys, bytes2Int64(itr.Key()))
+		require.NoError(t, db.Set(itr.Key(), []byte{1}))
+	}
+	require.NoError(t, itr.Error())
+	require.NoError(t, itr.Close())
+	require.Equal(t, expect, keys)
+
+	// similarly, a pruning loop should be able to delete keys while iterating, both within a batch
+	// and directly
+	ritr, err := db.ReverseIterator(nil, nil)
+	require.NoError(t, err)
+	batch := db.NewBatch()
+	keys = nil
+	for ; ritr.Valid(); ritr.Next() {
+		require.Equal(t, []byte{1}, ritr.Value())
+		key := bytes2Int64(ritr.Key())
+		keys = append([]int64{key}, keys...)
+		if key%2 == 0 {
+			require.NoError(t, db.Delete(ritr.Key()))
+		} else {
+			require.NoError(t, batch.Delete(ritr.Key()))
+		}
+	}
+	require.NoError(t, ritr.Error())
+	require.NoError(t, ritr.Close())
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	require.Equal(t, expect, keys)
+	assertKeyValues(t, db, map[string][]byte{})
+}
+
+func TestDBIteratorWritesAhead(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBIteratorWritesAhead(t, dbType)
+		})
+	}
+}
+
+func testDBIteratorWritesAhead(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+	defer db.Close()
+
+	// use more keys than bolt reads per page (1000), so that writes reach pages that haven't been
+	// read yet
+	const count = 3000
+	expect := make([]int64, 0, count)
+	for i := int64(0); i < count; i++ {
+		require.NoError(t, db.Set(int642Bytes(2*i), []byte{1}))
+		expect = append(expect, 2*i)
+	}
+
+	// insert a key after each iterated key, ahead of the iterator
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	var keys []int64
+	for ; itr.Valid(); itr.Next() {
+		key := bytes2Int64(itr.Key())
+		keys = append(keys, key)
+		if key%2 == 0 {
+			require.NoError(t, db.Set(int642Bytes(key+1), []byte{2}))
+		}
+	}
+	require.NoError(t, itr.Error())
+	require.NoError(t, itr.Close())
+
+	if db.Capabilities().IteratorSnapshot {
+		require.Equal(t, expect, keys)
+	} else {
+		// writing ahead of the iterator is outside the contract here, and the iterator sees the
+		// writes made ahead of its current page, but it still visits every key
+		require.Greater(t, len(keys), len(expect))
+		require.Subset(t, keys, expect)
+		require.True(t, sort.SliceIsSorted(keys, func(i, j int) bool { return keys[i] < keys[j] }))
+	}
+}
+
+func TestDBTxn(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBTxn(t, dbType)
+		})
+	}
+}
+
+func testDBTxn(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+	require.NoError(t, db.Set([]byte("c"), []byte{3}))
+
+	// reads should see the transaction's own writes, but the database should not until commit.
+	txn, err := db.NewTxn()
+	require.NoError(t, err)
+	require.NoError(t, txn.Set([]byte("c"), []byte{9}))
+	require.NoError(t, txn.Set([]byte("d"), []byte{4}))
+	require.NoError(t, txn.Delete([]byte("b")))
+
+	value, err := txn.Get([]byte("c"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{9}, value)
+	ok, err := txn.Has([]byte("b"))
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = txn.Has([]byte("d"))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, err = txn.Get(nil)
+	require.Equal(t, errKeyEmpty, err)
+	require.Equal(t, errKeyEmpty, txn.Set([]byte{}, []byte{1}))
+	require.Equal(t, errValueNil, txn.Set([]byte("e"), nil))
+	require.Equal(t, errKeyEmpty, txn.Delete(nil))
+	_, err = txn.Iterator([]byte{}, nil)
+	require.Equal(t, errKeyEmpty, err)
+
+	itr, err := txn.Iterator(nil, nil)
+	require.NoError(t, err)
+	var keys []string
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, fmt.Sprintf("%s:%d", itr.Key(), itr.Value()))
+	}
+	require.NoError(t, itr.Error())
+	require.NoError(t, itr.Close())
+	require.Equal(t, []string{"a:[1]", "c:[9]", "d:[4]"}, keys)
+
+	ritr, err := txn.ReverseIterator([]byte("b"), nil)
+	require.NoError(t, err)
+	keys = nil
+	for ; ritr.Valid(); ritr.Next() {
+		keys = append(keys, string(ritr.Key()))
+	}
+	require.NoError(t, ritr.Error())
+	require.NoError(t, ritr.Close())
+	require.Equal(t, []string{"d", "c"}, keys)
+
+	assertKeyValues(t, db, map[string][]byte{"a": {1}, "b": {2}, "c": {3}})
+	require.NoError(t, txn.Commit())
+	assertKeyValues(t, db, map[string][]byte{"a": {1}, "c": {9}, "d": {4}})
+
+	// the transaction can only be discarded once committed.
+	_, err = txn.Get([]byte("a"))
+	require.Equal(t, errTxnClosed, err)
+	require.Equal(t, errTxnClosed, txn.Set([]byte("a"), []byte{1}))
+	require.Equal(t, errTxnClosed, txn.Commit())
+	require.NoError(t, txn.Discard())
+
+	// a transaction should conflict if a key it read is modified before it commits, both by
+	// another transaction and by a direct write, and then have no effect.
+	for _, write := range []func() error{
+		func() error {
+			other, err := db.NewTxn()
+			if err != nil {
+				return err
+			}
+			defer other.Discard()
+			if err = other.Set([]byte("a"), []byte{7}); err != nil {
+				return err
+			}
+			return other.Commit()
+		},
+		func() error { return db.Delete([]byte("a")) },
+	} {
+		txn, err = db.NewTxn()
+		require.NoError(t, err)
+		_, err = txn.Get([]byte("a"))
+		require.NoError(t, err)
+		require.NoError(t, txn.Set([]byte("e"), []byte{5}))
+
+		require.NoError(t, write())
+
+		err = txn.Commit()
+		var conflict *ConflictError
+		require.True(t, errors.As(err, &conflict), "expected conflict, got %v", err)
+		require.NoError(t, txn.Discard())
+		ok, err = db.Has([]byte("e"))
+		require.NoError(t, err)
+		require.False(t, ok)
+	}
+
+	// blind writes should not conflict.
+	txn, err = db.NewTxn()
+	require.NoError(t, err)
+	require.NoError(t, txn.Set([]byte("c"), []byte{1}))
+	require.NoError(t, db.Set([]byte("c"), []byte{2}))
+	require.NoError(t, txn.Commit())
+	require.NoError(t, txn.Discard())
+	assertKeyValues(t, db, map[string][]byte{"c": {1}, "d": {4}})
+
+	// discarded transactions should have no effect.
+	txn, err = db.NewTxn()
+	require.NoError(t, err)
+	require.NoError(t, txn.Set([]byte("f"), []byte{6}))
+	require.NoError(t, txn.Discard())
+	require.NoError(t, txn.Discard())
+	assertKeyValues(t, db, map[string][]byte{"c": {1}, "d": {4}})
+}
+
+func TestDBIndexedBatch(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBIndexedBatch(t, dbType)
+		})
+	}
+}
+
+func testDBIndexedBatch(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+
+	for _, key := range []string{"a", "b", "c", "e"} {
+		require.NoError(t, db.Set([]byte(key), []byte{1}))
+	}
+
+	batch := NewIndexedBatch(db)
+	defer batch.Close()
+	require.NoError(t, batch.Delete([]byte("a")))
+	require.NoError(t, batch.Set([]byte("c"), []byte{2}))
+	require.NoError(t, batch.Set([]byte("d"), []byte{2}))
+	require.NoError(t, batch.Delete([]byte("d")))
+	require.NoError(t, batch.Delete([]byte("x")))
+	require.NoError(t, batch.Set([]byte("f"), []byte{2}))
+
+	require.Equal(t, errKeyEmpty, batch.Set(nil, []byte{1}))
+	require.Equal(t, errValueNil, batch.Set([]byte("a"), nil))
+	_, err = batch.Get([]byte{})
+	require.Equal(t, errKeyEmpty, err)
+	_, err = batch.ReverseIterator(nil, []byte{})
+	require.Equal(t, errKeyEmpty, err)
+
+	for key, expect := range map[string][]byte{"a": nil, "b": {1}, "c": {2}, "d": nil, "e": {1}, "f": {2}} {
+		value, err := batch.Get([]byte(key))
+		require.NoError(t, err)
+		require.Equal(t, expect, value, key)
+		ok, err := batch.Has([]byte(key))
+		require.NoError(t, err)
+		require.Equal(t, expect != nil, ok, key)
+	}
+
+	itr, err := batch.Iterator(nil, nil)
+	require.NoError(t, err)
+	var keys []string
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, fmt.Sprintf("%s:%d", itr.Key(), itr.Value()))
+	}
+	require.NoError(t, itr.Error())
+	require.NoError(t, itr.Close())
+	require.Equal(t, []string{"b:[1]", "c:[2]", "e:[1]", "f:[2]"}, keys)
+
+	ritr, err := batch.ReverseIterator([]byte("a"), []byte("f"))
+	require.NoError(t, err)
+	keys = nil
+	for ; ritr.Valid(); ritr.Next() {
+		keys = append(keys, string(ritr.Key()))
+	}
+	require.NoError(t, ritr.Error())
+	require.NoError(t, ritr.Close())
+	require.Equal(t, []string{"e", "c", "b"}, keys)
+
+	// the database should be unaffected until the batch is written.
+	assertKeyValues(t, db, map[string][]byte{"a": {1}, "b": {1}, "c": {1}, "e": {1}})
+	require.NoError(t, batch.Write())
+	assertKeyValues(t, db, map[string][]byte{"b": {1}, "c": {2}, "e": {1}, "f": {2}})
+
+	_, err = batch.Get([]byte("a"))
+	require.Equal(t, errBatchClosed, err)
+	_, err = batch.Iterator(nil, nil)
+	require.Equal(t, errBatchClosed, err)
+	require.Equal(t, errBatchClosed, batch.Write())
+	require.NoError(t, batch.Close())
+}
+
+func TestDBDeleteRange(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBDeleteRange(t, dbType)
+		})
+	}
+}
+
+func testDBDeleteRange(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+
+	reset := func() {
+		require.NoError(t, db.DeleteRange(nil, nil))
+		for i := int64(0); i < 10; i++ {
+			require.NoError(t, db.Set(int642Bytes(i), []byte{1}))
+		}
+	}
+	expect := func(keys ...int64) {
+		values := make(map[string][]byte, len(keys))
+		for _, key := range keys {
+			values[string(int642Bytes(key))] = []byte{1}
+		}
+		assertKeyValues(t, db, values)
+	}
+
+	require.Equal(t, errKeyEmpty, db.DeleteRange([]byte{}, nil))
+	require.Equal(t, errKeyEmpty, db.DeleteRange(nil, []byte{}))
+
+	reset()
+	require.NoError(t, db.DeleteRange(int642Bytes(2), int642Bytes(5)))
+	expect(0, 1, 5, 6, 7, 8, 9)
+	require.NoError(t, db.DeleteRange(nil, int642Bytes(1)))
+	expect(1, 5, 6, 7, 8, 9)
+	require.NoError(t, db.DeleteRange(int642Bytes(7), nil))
+	expect(1, 5, 6)
+	require.NoError(t, db.DeleteRange(int642Bytes(2), int642Bytes(4)))
+	expect(1, 5, 6)
+	require.NoError(t, db.DeleteRange(nil, nil))
+	expect()
+
+	// batches should delete keys set earlier in the batch, but not later.
+	reset()
+	batch := db.NewBatch()
+	require.NoError(t, batch.Set(int642Bytes(10), []byte{1}))
+	require.NoError(t, batch.Set(int642Bytes(11), []byte{1}))
+	require.NoError(t, batch.Delete(int642Bytes(1)))
+	require.NoError(t, batch.DeleteRange(int642Bytes(5), nil))
+	require.NoError(t, batch.Set(int642Bytes(6), []byte{1}))
+	require.Equal(t, errKeyEmpty, batch.DeleteRange(nil, []byte{}))
+	expect(0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	expect(0, 2, 3, 4, 6)
+
+	reset()
+	ibatch := NewIndexedBatch(db)
+	defer ibatch.Close()
+	require.NoError(t, ibatch.Set(int642Bytes(10), []byte{1}))
+	require.NoError(t, ibatch.DeleteRange(int642Bytes(1), int642Bytes(9)))
+	require.NoError(t, ibatch.Set(int642Bytes(5), []byte{1}))
+	itr, err := ibatch.Iterator(nil, nil)
+	require.NoError(t, err)
+	verifyIterator(t, itr, []int64{0, 5, 9, 10}, "indexed batch iterator after DeleteRange")
+	require.NoError(t, itr.Close())
+	require.NoError(t, ibatch.Write())
+	expect(0, 5, 9, 10)
+}
+
+func TestDBBatchSize(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBBatchSize(t, dbType)
+		})
+	}
+}
+
+func testDBBatchSize(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+
+	batch := db.NewBatch()
+	defer batch.Close()
+	require.Zero(t, batch.Len())
+	size, err := batch.GetByteSize()
+	require.NoError(t, err)
+	require.Zero(t, size)
+
+	require.NoError(t, batch.Set([]byte("a"), []byte{1, 2, 3}))
+	require.Equal(t, 1, batch.Len())
+	size, err = batch.GetByteSize()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, size, 4)
+
+	prevSize := size
+	require.NoError(t, batch.Delete([]byte("b")))
+	require.Equal(t, 2, batch.Len())
+	size, err = batch.GetByteSize()
+	require.NoError(t, err)
+	require.Greater(t, size, prevSize)
+
+	// failed operations should not count.
+	require.Error(t, batch.Set(nil, []byte{1}))
+	require.Equal(t, 2, batch.Len())
+
+	// range deletions count as one operation per deleted key, unless they are native. c is both
+	// in the database and set in the batch, but only counts once.
+	require.NoError(t, db.Set([]byte("c"), []byte{3}))
+	require.NoError(t, db.Set([]byte("d"), []byte{4}))
+	require.NoError(t, batch.Set([]byte("c"), []byte{5}))
+	require.Equal(t, 3, batch.Len())
+	require.NoError(t, batch.DeleteRange([]byte("a"), []byte("e")))
+	if db.Capabilities().RangeDelete {
+		require.Equal(t, 4, batch.Len())
+	} else {
+		require.Equal(t, 6, batch.Len()) // a, c and d
+	}
+
+	require.NoError(t, batch.Write())
+	require.Zero(t, batch.Len())
+	_, err = batch.GetByteSize()
+	require.Equal(t, errBatchClosed, err)
+}
+
+func TestDBMultiGet(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBMultiGet(t, dbType)
+		})
+	}
+}
+
+func testDBMultiGet(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+
+	values, err := db.MultiGet([][]byte{[]byte("b"), []byte("x"), []byte("a"), []byte("b")})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{{2}, nil, {1}, {2}}, values)
+
+	values, err = db.MultiGet(nil)
+	require.NoError(t, err)
+	require.Empty(t, values)
+
+	_, err = db.MultiGet([][]byte{[]byte("a"), {}})
+	require.Equal(t, errKeyEmpty, err)
+	_, err = db.MultiGet([][]byte{nil})
+	require.Equal(t, errKeyEmpty, err)
+}
+
+func TestDBIteratorSeek(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBIteratorSeek(t, dbType)
+		})
+	}
+}
+
+func testDBIteratorSeek(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+
+	for _, i := range []int64{1, 3, 5, 7, 9} {
+		require.NoError(t, db.Set(int642Bytes(i), []byte{byte(i)}))
+	}
+
+	testCases := []struct {
+		start, end int64 // 0 means nil
+		reverse    bool
+		seek       int64
+		expect     []int64
+	}{
+		{0, 0, false, 4, []int64{5, 7, 9}},
+		{0, 0, false, 5, []int64{5, 7, 9}},
+		{0, 0, false, 0, []int64{1, 3, 5, 7, 9}},
+		{0, 0, false, 10, nil},
+		{3, 8, false, 1, []int64{3, 5, 7}},
+		{3, 8, false, 6, []int64{7}},
+		{3, 8, false, 8, nil},
+		{0, 0, true, 6, []int64{5, 3, 1}},
+		{0, 0, true, 5, []int64{5, 3, 1}},
+		{0, 0, true, 10, []int64{9, 7, 5, 3, 1}},
+		{0, 0, true, 0, nil},
+		{3, 8, true, 9, []int64{7, 5, 3}},
+		{3, 8, true, 4, []int64{3}},
+		{3, 8, true, 2, nil},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		var start, end []byte
+		if tc.start != 0 {
+			start = int642Bytes(tc.start)
+		}
+		if tc.end != 0 {
+			end = int642Bytes(tc.end)
+		}
+		msg := fmt.Sprintf("[%v, %v) reverse=%v seek=%v", tc.start, tc.end, tc.reverse, tc.seek)
+
+		var itr Iterator
+		if tc.reverse {
+			itr, err = db.ReverseIterator(start, end)
+		} else {
+			itr, err = db.Iterator(start, end)
+		}
+		require.NoError(t, err)
+
+		// Seek from the initial position, and again after exhausting the iterator.
+		itr.Seek(int642Bytes(tc.seek))
+		verifyIterator(t, itr, tc.expect, msg)
+		itr.Seek(int642Bytes(tc.seek))
+		verifyIterator(t, itr, tc.expect, msg+" (exhausted)")
+
+		gotStart, gotEnd := itr.Domain()
+		require.Equal(t, start, gotStart, msg)
+		require.Equal(t, end, gotEnd, msg)
+		require.NoError(t, itr.Error())
+		require.NoError(t, itr.Close())
+	}
+
+	// Seeking backwards, and seeking within a prefixed database.
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	itr.Seek(int642Bytes(7))
+	require.True(t, itr.Valid())
+	require.Equal(t, int642Bytes(7), itr.Key())
+	require.Equal(t, []byte{7}, itr.Value())
+	itr.Seek(int642Bytes(2))
+	verifyIterator(t, itr, []int64{3, 5, 7, 9}, "seek backwards")
+	require.NoError(t, itr.Close())
+
+	pdb := NewPrefixDB(db, int642Bytes(0)[:4])
+	itr, err = pdb.ReverseIterator(nil, nil)
+	require.NoError(t, err)
+	itr.Seek(int642Bytes(6)[4:])
+	require.True(t, itr.Valid())
+	require.Equal(t, int642Bytes(5)[4:], itr.Key())
+	itr.Seek(int642Bytes(0)[:1])
+	require.False(t, itr.Valid())
+	require.NoError(t, itr.Close())
+}
+
+func TestDBIteratorWithOptions(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBIteratorWithOptions(t, dbType)
+		})
+	}
+}
+
+func testDBIteratorWithOptions(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+
+	for _, i := range []int64{1, 3, 5, 7, 9} {
+		require.NoError(t, db.Set(int642Bytes(i), []byte{byte(i)}))
+	}
+
+	testCases := []struct {
+		opts   IteratorOptions
+		expect []int64
+	}{
+		{IteratorOptions{}, []int64{3, 5, 7}},
+		{IteratorOptions{Reverse: true}, []int64{7, 5, 3}},
+		{IteratorOptions{KeysOnly: true}, []int64{3, 5, 7}},
+		{IteratorOptions{Reverse: true, KeysOnly: true}, []int64{7, 5, 3}},
+		{IteratorOptions{DontFillCache: true, ReadaheadSize: 1 << 20}, []int64{3, 5, 7}},
+		{IteratorOptions{PrefetchSize: 1}, []int64{3, 5, 7}},
+		{IteratorOptions{Reverse: true, PrefetchSize: 2}, []int64{7, 5, 3}},
+	}
+	for _, tc := range testCases {
+		msg := fmt.Sprintf("%+v", tc.opts)
+		itr, err := db.IteratorWithOptions(int642Bytes(2), int642Bytes(8), tc.opts)
+		require.NoError(t, err)
+
+		var keys []int64
+		for ; itr.Valid(); itr.Next() {
+			key := bytes2Int64(itr.Key())
+			keys = append(keys, key)
+			if tc.opts.KeysOnly {
+				require.Nil(t, itr.Value(), msg)
+			} else {
+				require.Equal(t, []byte{byte(key)}, itr.Value(), msg)
+			}
+		}
+		require.Equal(t, tc.expect, keys, msg)
+		require.NoError(t, itr.Error())
+
+		start, end := itr.Domain()
+		require.Equal(t, int642Bytes(2), start, msg)
+		require.Equal(t, int642Bytes(8), end, msg)
+		require.NoError(t, itr.Close())
+	}
+
+	_, err = db.IteratorWithOptions([]byte{}, nil, IteratorOptions{})
+	require.Equal(t, errKeyEmpty, err)
+	_, err = db.IteratorWithOptions(nil, []byte{}, IteratorOptions{Reverse: true})
+	require.Equal(t, errKeyEmpty, err)
+}
+
+func TestNewDBWithOptions(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testNewDBWithOptions(t, dbType)
+		})
+	}
+}
+
+func testNewDBWithOptions(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDBWithOptions(name, backend, dir, OptionsMap{
+		"block_cache_size": 8 << 20,
+		"max_open_files":   int64(64),
+		"no_sync":          "true",
+		"unknown":          []string{"ignored"},
+	})
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+
+	require.NoError(t, db.SetSync([]byte("a"), []byte{1}))
+	value, err := db.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, value)
+	require.NoError(t, db.Close())
+}
+
+func TestRegisterBackend(t *testing.T) {
+	const backend BackendType = "test_registered"
+	var gotName, gotDir string
+	var gotOpts Options
+	RegisterBackend(backend, func(name, dir string, opts Options) (DB, error) {
+		gotName, gotDir, gotOpts = name, dir, opts
+		return NewMemDB(), nil
+	})
+	defer func() {
+		backendsMtx.Lock()
+		delete(backends, backend)
+		backendsMtx.Unlock()
+	}()
+
+	db, err := NewDBWithOptions("name", backend, "dir", OptionsMap{"key": "value"})
+	require.NoError(t, err)
+	require.IsType(t, &MemDB{}, db)
+	require.Equal(t, "name", gotName)
+	require.Equal(t, "dir", gotDir)
+	require.Equal(t, "value", gotOpts.Get("key"))
+
+	// NewDB passes empty options, rather than a nil interface.
+	_, err = NewDB("name", backend, "dir")
+	require.NoError(t, err)
+	require.Nil(t, gotOpts.Get("key"))
+
+	// Registering again replaces the backend.
+	RegisterBackend(backend, func(name, dir string, opts Options) (DB, error) {
+		return nil, errors.New("replaced")
+	})
+	_, err = NewDB("name", backend, "dir")
+	require.EqualError(t, err, "failed to initialize database: replaced")
+
+	_, err = NewDB("name", "unknown", "dir")
+	require.Error(t, err)
+}
+
+func TestDBCapabilities(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBCapabilities(t, dbType)
+		})
+	}
+}
+
+func testDBCapabilities(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+	defer db.Close()
+
+	caps := db.Capabilities()
+	snapshot, err := db.Snapshot()
+	if caps.Snapshot {
+		require.NoError(t, err)
+		require.NoError(t, snapshot.Close())
+	} else {
+		require.Error(t, err)
+	}
+
+	cdb := WithContext(context.Background(), db)
+	require.Equal(t, caps, cdb.Capabilities())
+	require.Equal(t, caps, NewPrefixDB(db, []byte("p")).Capabilities())
+}
+
+func TestBackends(t *testing.T) {
+	types := Backends()
+	require.Len(t, types, len(backends))
+	require.True(t, sort.SliceIsSorted(types, func(i, j int) bool { return types[i] < types[j] }))
+	require.Contains(t, types, GoLevelDBBackend)
+	require.Contains(t, types, MemDBBackend)
+
+	_, err := NewDB("name", "unknown", "dir")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "goleveldb,memdb,prefixdb")
+}
+
+func TestDBTypedStats(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBTypedStats(t, dbType)
+		})
+	}
+}
+
+func testDBTypedStats(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+
+	for i := int64(0); i < 100; i++ {
+		require.NoError(t, db.Set(int642Bytes(i), []byte{1}))
+	}
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+
+	stats := db.TypedStats()
+	for _, value := range []int64{
+		stats.ApproximateKeys, stats.DiskSize, stats.MemtableSize, stats.CacheHits,
+		stats.CacheMisses, stats.CompactionPendingBytes, stats.OpenIterators,
+	} {
+		require.GreaterOrEqual(t, value, int64(-1), "%+v", stats)
+	}
+	if stats.OpenIterators != -1 {
+		require.GreaterOrEqual(t, stats.OpenIterators, int64(1))
+	}
+	require.NoError(t, itr.Close())
+
+	if stats := db.TypedStats(); stats.OpenIterators != -1 {
+		require.Zero(t, stats.OpenIterators)
+	}
+
+	// The raw stats remain available.
+	require.NotPanics(t, func() { db.Stats() })
+
+	if backend == MemDBBackend {
+		require.EqualValues(t, 100, stats.ApproximateKeys)
+		require.Zero(t, stats.DiskSize)
+	}
+}
+
+func TestDBReadOnly(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBReadOnly(t, dbType)
+		})
+	}
+}
+
+func testDBReadOnly(t *testing.T, backend BackendType) {
+	if backend == MemDBBackend || backend == "prefixdb" {
+		t.Skip("in-memory backends can't be reopened")
+	}
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	defer cleanupDBDir(dir, name)
+
+	// A missing database can't be opened read-only.
+	_, err := NewDBWithOptions(name, backend, dir, OptionsMap{"read_only": true})
+	require.Error(t, err)
+
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+	require.NoError(t, db.Close())
+
+	db, err = NewDBWithOptions(name, backend, dir, OptionsMap{"read_only": true})
+	require.NoError(t, err)
+	defer db.Close()
+
+	value, err := db.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, value)
+	assertKeyValues(t, db, map[string][]byte{"a": {1}, "b": {2}})
+
+	var readOnlyErr *ReadOnlyError
+	require.True(t, errors.As(db.Set([]byte("c"), []byte{3}), &readOnlyErr))
+	require.True(t, errors.As(db.Delete([]byte("a")), &readOnlyErr))
+	require.True(t, errors.As(db.DeleteRange(nil, nil), &readOnlyErr))
+	batch := db.NewBatch()
+	require.True(t, errors.As(batch.Set([]byte("c"), []byte{3}), &readOnlyErr))
+	require.True(t, errors.As(batch.Write(), &readOnlyErr))
+	require.NoError(t, batch.Close())
+
+	value, err = db.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, value)
+}
+
+func TestDBCheckpoint(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBCheckpoint(t, dbType)
+		})
+	}
+}
+
+func testDBCheckpoint(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+
+	checkpointDir, err := ioutil.TempDir("", "checkpoint")
+	require.NoError(t, err)
+	defer os.RemoveAll(checkpointDir)
+
+	// The checkpoint directory must not exist.
+	require.Error(t, db.Checkpoint(checkpointDir))
+
+	checkpointDir = filepath.Join(checkpointDir, "checkpoint")
+	require.NoError(t, db.Checkpoint(checkpointDir))
+
+	// The database remains usable, and later writes don't affect the checkpoint.
+	require.NoError(t, db.Set([]byte("c"), []byte{3}))
+	require.NoError(t, db.Delete([]byte("a")))
+	assertKeyValues(t, db, map[string][]byte{"b": {2}, "c": {3}})
+	require.NoError(t, db.Close())
+
+	var checkpoint DB
+	switch backend {
+	case MemDBBackend:
+		checkpoint, err = NewMemDBFromCheckpoint(checkpointDir)
+	case "prefixdb":
+		t.Skip("test backend can't be reopened")
+	default:
+		checkpoint, err = NewDB(name, backend, checkpointDir)
+	}
+	require.NoError(t, err)
+	assertKeyValues(t, checkpoint, map[string][]byte{"a": {1}, "b": {2}})
+	require.NoError(t, checkpoint.Close())
+}
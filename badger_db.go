@@ -5,33 +5,111 @@ package db
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
 	"github.com/dgraph-io/badger/v3"
 )
 
+// Maximum number of pending writes when loading a checkpoint.
+const badgerCheckpointPendingWrites = 256
+
 func init() { registerDBCreator(BadgerDBBackend, badgerDBCreator, true) }
 
-func badgerDBCreator(dbName, dir string) (DB, error) {
-	return NewBadgerDB(dbName, dir)
+func badgerDBCreator(dbName, dir string, opts Options) (DB, error) {
+	readOnly, err := optBool(opts, "read_only", false)
+	if err != nil {
+		return nil, err
+	}
+	path, err := badgerDBPath(dbName, dir, !readOnly)
+	if err != nil {
+		return nil, err
+	}
+	bopts, err := badgerDBOptions(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewBadgerDBWithOptions(bopts)
 }
 
 // NewBadgerDB creates a Badger key-value store backed to the
 // directory dir supplied. If dir does not exist, it will be created.
 func NewBadgerDB(dbName, dir string) (*BadgerDB, error) {
+	path, err := badgerDBPath(dbName, dir, true)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := badgerDBOptions(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewBadgerDBWithOptions(opts)
+}
+
+// badgerDBPath returns the path of the database directory, creating it if create is true.
+func badgerDBPath(dbName, dir string, create bool) (string, error) {
 	// Since Badger doesn't support database names, we join both to obtain
 	// the final directory to use for the database.
 	path := filepath.Join(dir, dbName)
 
+	if !create {
+		return path, nil
+	}
 	if err := os.MkdirAll(path, 0o755); err != nil {
-		return nil, err
+		return "", err
 	}
-	opts := badger.DefaultOptions(path)
-	opts.SyncWrites = false // note that we have Sync methods
-	opts.Logger = nil       // badger is too chatty by default
-	return NewBadgerDBWithOptions(opts)
+	return path, nil
+}
+
+// badgerDBOptions converts options passed to NewDBWithOptions. Supported options are:
+//
+//   - sync_writes: whether to sync all writes to disk, not just sync writes
+//   - block_cache_size: the size of the block cache, in bytes
+//   - index_cache_size: the size of the index cache, in bytes
+//   - value_log_file_size: the maximum size of value log files, in bytes
+//   - num_versions_to_keep: the number of versions to keep per key
+//   - read_only: whether to open an existing database read-only, which fails while another
+//     process has it open for writing
+//
+// Unset options default to badger.DefaultOptions, except that writes aren't synced.
+func badgerDBOptions(path string, opts Options) (badger.Options, error) {
+	o := badger.DefaultOptions(path)
+	o.SyncWrites = false // note that we have Sync methods
+	o.Logger = nil       // badger is too chatty by default
+
+	syncWrites, err := optBool(opts, "sync_writes", o.SyncWrites)
+	if err != nil {
+		return o, err
+	}
+	blockCacheSize, err := optInt(opts, "block_cache_size", int(o.BlockCacheSize))
+	if err != nil {
+		return o, err
+	}
+	indexCacheSize, err := optInt(opts, "index_cache_size", int(o.IndexCacheSize))
+	if err != nil {
+		return o, err
+	}
+	valueLogFileSize, err := optInt(opts, "value_log_file_size", int(o.ValueLogFileSize))
+	if err != nil {
+		return o, err
+	}
+	numVersionsToKeep, err := optInt(opts, "num_versions_to_keep", o.NumVersionsToKeep)
+	if err != nil {
+		return o, err
+	}
+	readOnly, err := optBool(opts, "read_only", o.ReadOnly)
+	if err != nil {
+		return o, err
+	}
+	return o.WithSyncWrites(syncWrites).
+		WithReadOnly(readOnly).
+		WithBlockCacheSize(int64(blockCacheSize)).
+		WithIndexCacheSize(int64(indexCacheSize)).
+		WithValueLogFileSize(int64(valueLogFileSize)).
+		WithNumVersionsToKeep(numVersionsToKeep), nil
 }
 
 // NewBadgerDBWithOptions creates a BadgerDB key value store
@@ -49,43 +127,81 @@ type BadgerDB struct {
 	db *badger.DB
 }
 
-var _ DB = (*BadgerDB)(nil)
+var (
+	_ DB               = (*BadgerDB)(nil)
+	_ PrefixIteratorDB = (*BadgerDB)(nil)
+)
 
 func (b *BadgerDB) Get(key []byte) ([]byte, error) {
 	if len(key) == 0 {
 		return nil, errKeyEmpty
 	}
 	var val []byte
-	err := b.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(key)
-		if err == badger.ErrKeyNotFound {
-			return nil
-		} else if err != nil {
-			return err
-		}
-		val, err = item.ValueCopy(nil)
-		if err == nil && val == nil {
-			val = []byte{}
-		}
+	err := b.db.View(func(txn *badger.Txn) (err error) {
+		val, err = badgerGet(txn, key)
 		return err
 	})
 	return val, err
 }
 
+// badgerGet fetches the value of the given key within txn, or nil if it does not exist.
+func badgerGet(txn *badger.Txn, key []byte) ([]byte, error) {
+	item, err := txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	val, err := item.ValueCopy(nil)
+	if err == nil && val == nil {
+		val = []byte{}
+	}
+	return val, err
+}
+
 func (b *BadgerDB) Has(key []byte) (bool, error) {
 	if len(key) == 0 {
 		return false, errKeyEmpty
 	}
 	var found bool
+	err := b.db.View(func(txn *badger.Txn) (err error) {
+		found, err = badgerHas(txn, key)
+		return err
+	})
+	return found, err
+}
+
+// badgerHas checks if the given key exists within txn.
+func badgerHas(txn *badger.Txn, key []byte) (bool, error) {
+	_, err := txn.Get(key)
+	if err != nil && err != badger.ErrKeyNotFound {
+		return false, err
+	}
+	return err != badger.ErrKeyNotFound, nil
+}
+
+// MultiGet reads all keys within a single read-only transaction.
+func (b *BadgerDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+	}
+	values := make([][]byte, len(keys))
 	err := b.db.View(func(txn *badger.Txn) error {
-		_, err := txn.Get(key)
-		if err != nil && err != badger.ErrKeyNotFound {
-			return err
+		for i, key := range keys {
+			value, err := badgerGet(txn, key)
+			if err != nil {
+				return err
+			}
+			values[i] = value
 		}
-		found = (err != badger.ErrKeyNotFound)
 		return nil
 	})
-	return found, err
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
 }
 
 func (b *BadgerDB) Set(key, value []byte) error {
@@ -124,6 +240,36 @@ func (b *BadgerDB) DeleteSync(key []byte) error {
 	return withSync(b.db, b.Delete(key))
 }
 
+// DeleteRange deletes the keys in the range one by one in a WriteBatch, since Badger has no
+// native range deletion.
+func (b *BadgerDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	wb := b.db.NewWriteBatch()
+	if err := badgerDeleteRange(b.db, start, end, wb.Delete); err != nil {
+		wb.Cancel()
+		return err
+	}
+	return wb.Flush()
+}
+
+// badgerDeleteRange calls del for all keys in the range.
+func badgerDeleteRange(db *badger.DB, start, end []byte, del func([]byte) error) error {
+	return db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		itr := newBadgerDBIterator(txn, start, end, opts, false)
+		defer itr.Close()
+		for ; itr.Valid(); itr.Next() {
+			if err := del(itr.Key()); err != nil {
+				return err
+			}
+		}
+		return itr.Error()
+	})
+}
+
 func (b *BadgerDB) Close() error {
 	return b.db.Close()
 }
@@ -137,6 +283,12 @@ func (b *BadgerDB) iteratorOpts(start, end []byte, opts badger.IteratorOptions)
 		return nil, errKeyEmpty
 	}
 	txn := b.db.NewTransaction(false)
+	return newBadgerDBIterator(txn, start, end, opts, true), nil
+}
+
+// newBadgerDBIterator creates a new badgerDBIterator within txn. If ownsTxn is true, the
+// transaction is discarded when the iterator is closed.
+func newBadgerDBIterator(txn *badger.Txn, start, end []byte, opts badger.IteratorOptions, ownsTxn bool) *badgerDBIterator {
 	iter := txn.NewIterator(opts)
 	iter.Rewind()
 	iter.Seek(start)
@@ -150,9 +302,10 @@ func (b *BadgerDB) iteratorOpts(start, end []byte, opts badger.IteratorOptions)
 		start:   start,
 		end:     end,
 
-		txn:  txn,
-		iter: iter,
-	}, nil
+		txn:     txn,
+		ownsTxn: ownsTxn,
+		iter:    iter,
+	}
 }
 
 func (b *BadgerDB) Iterator(start, end []byte) (Iterator, error) {
@@ -166,10 +319,123 @@ func (b *BadgerDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	return b.iteratorOpts(end, start, opts)
 }
 
+// IteratorWithOptions implements DB. Key-only iterators don't prefetch values, nor read them from
+// the value log.
+func (b *BadgerDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	bopts := badger.DefaultIteratorOptions
+	bopts.Reverse = opts.Reverse
+	bopts.PrefetchValues = !opts.KeysOnly
+	if opts.PrefetchSize > 0 {
+		bopts.PrefetchSize = opts.PrefetchSize
+	}
+	if opts.Reverse {
+		start, end = end, start
+	}
+	itr, err := b.iteratorOpts(start, end, bopts)
+	if err != nil {
+		return nil, err
+	}
+	itr.keysOnly = opts.KeysOnly
+	return itr, nil
+}
+
+// IteratePrefix implements PrefixIteratorDB, using Badger's prefix iteration, which skips tables
+// that don't contain the prefix.
+func (b *BadgerDB) IteratePrefix(prefix []byte) (Iterator, error) {
+	start, end := prefixRange(prefix)
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = start
+	return b.iteratorOpts(start, end, opts)
+}
+
+// ReverseIteratePrefix implements PrefixIteratorDB. Badger's prefix iteration stops at the first
+// key without the prefix, including the key that reverse iterators are positioned at before
+// stepping back into the domain, so this is a plain reverse iterator over the domain.
+func (b *BadgerDB) ReverseIteratePrefix(prefix []byte) (Iterator, error) {
+	return b.ReverseIterator(prefixRange(prefix))
+}
+
 func (b *BadgerDB) Stats() map[string]string {
 	return nil
 }
 
+// TypedStats implements DB. The key count includes older versions and deletions that have yet to
+// be compacted.
+func (b *BadgerDB) TypedStats() DBStats {
+	stats := newDBStats()
+	var keys int64
+	for _, table := range b.db.Tables() {
+		keys += int64(table.KeyCount)
+	}
+	stats.ApproximateKeys = keys
+	lsm, vlog := b.db.Size()
+	stats.DiskSize = lsm + vlog
+	if metrics := b.db.BlockCacheMetrics(); metrics != nil {
+		stats.CacheHits = int64(metrics.Hits())
+		stats.CacheMisses = int64(metrics.Misses())
+	}
+	return stats
+}
+
+// Capabilities implements DB. Batches are written using Badger's WriteBatch, which commits large
+// batches in several transactions, so they are not atomic.
+func (b *BadgerDB) Capabilities() Capabilities {
+	return Capabilities{
+		SyncWrites:       true,
+		Snapshot:         true,
+		IteratorSnapshot: true,
+		Compaction:       true,
+	}
+}
+
+// Snapshot implements DB. The snapshot holds a read-only transaction open until it is closed.
+func (b *BadgerDB) Snapshot() (Snapshot, error) {
+	return &badgerDBSnapshot{txn: b.db.NewTransaction(false)}, nil
+}
+
+// NewTxn implements DB, using Badger's native transactions. Badger only allows one iterator to be
+// open at a time in a read-write transaction.
+func (b *BadgerDB) NewTxn() (Txn, error) {
+	return &badgerDBTxn{txn: b.db.NewTransaction(true)}, nil
+}
+
+// Checkpoint implements DB. The latest versions of all keys are streamed with Badger's backup
+// format into a new database with the default options.
+func (b *BadgerDB) Checkpoint(dir string) error {
+	if err := createCheckpointDir(dir); err != nil {
+		return err
+	}
+	path, err := badgerDBPath(filepath.Base(b.db.Opts().Dir), dir, true)
+	if err != nil {
+		return err
+	}
+	opts, err := badgerDBOptions(path, nil)
+	if err != nil {
+		return err
+	}
+	target, err := badger.Open(opts)
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	backupErr := make(chan error, 1)
+	go func() {
+		_, err := b.db.Backup(pw, 0)
+		pw.CloseWithError(err)
+		backupErr <- err
+	}()
+	err = target.Load(pr, badgerCheckpointPendingWrites)
+	pr.CloseWithError(err) // unblock the backup if loading failed
+	if berr := <-backupErr; err == nil {
+		err = berr
+	}
+	if cerr := target.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
 func (b *BadgerDB) NewBatch() Batch {
 	wb := &badgerDBBatch{
 		db:         b.db,
@@ -183,8 +449,13 @@ func (b *BadgerDB) NewBatch() Batch {
 var _ Batch = (*badgerDBBatch)(nil)
 
 type badgerDBBatch struct {
-	db *badger.DB
-	wb *badger.WriteBatch
+	db   *badger.DB
+	wb   *badger.WriteBatch
+	keys [][]byte // keys set in the batch, since write batches can't be read back for DeleteRange
+
+	// Write batches don't expose their number of operations or size either.
+	count int
+	size  int
 
 	// Calling db.Flush twice panics, so we must keep track of whether we've
 	// flushed already on our own. If Write can receive from the firstFlush
@@ -202,14 +473,64 @@ func (b *badgerDBBatch) Set(key, value []byte) error {
 	if value == nil {
 		return errValueNil
 	}
-	return b.wb.Set(key, value)
+	if err := b.wb.Set(key, value); err != nil {
+		return err
+	}
+	b.keys = append(b.keys, key)
+	b.count++
+	b.size += len(key) + len(value)
+	return nil
 }
 
 func (b *badgerDBBatch) Delete(key []byte) error {
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
-	return b.wb.Delete(key)
+	return b.delete(key)
+}
+
+func (b *badgerDBBatch) delete(key []byte) error {
+	if err := b.wb.Delete(key); err != nil {
+		return err
+	}
+	b.count++
+	b.size += len(key)
+	return nil
+}
+
+// DeleteRange resolves the range to the keys present in the database and the batch when it is
+// called.
+func (b *badgerDBBatch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	itr := newBadgerDBIterator(b.db.NewTransaction(false), start, end, opts, true)
+	keys, err := deleteRangeKeys(itr, b.keys, start, end)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := b.delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *badgerDBBatch) GetByteSize() (int, error) {
+	if len(b.firstFlush) == 0 {
+		return 0, errBatchClosed
+	}
+	return b.size, nil
+}
+
+func (b *badgerDBBatch) Len() int {
+	if len(b.firstFlush) == 0 {
+		return 0
+	}
+	return b.count
 }
 
 func (b *badgerDBBatch) Write() error {
@@ -234,24 +555,165 @@ func (b *badgerDBBatch) Close() error {
 	return nil
 }
 
+var _ Snapshot = (*badgerDBSnapshot)(nil)
+
+type badgerDBSnapshot struct {
+	txn *badger.Txn
+}
+
+func (s *badgerDBSnapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	return badgerGet(s.txn, key)
+}
+
+func (s *badgerDBSnapshot) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	return badgerHas(s.txn, key)
+}
+
+func (s *badgerDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return newBadgerDBIterator(s.txn, start, end, badger.DefaultIteratorOptions, false), nil
+}
+
+func (s *badgerDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	opts := badger.DefaultIteratorOptions
+	opts.Reverse = true
+	return newBadgerDBIterator(s.txn, end, start, opts, false), nil
+}
+
+func (s *badgerDBSnapshot) Close() error {
+	s.txn.Discard()
+	return nil
+}
+
+var _ Txn = (*badgerDBTxn)(nil)
+
+type badgerDBTxn struct {
+	txn    *badger.Txn
+	closed bool
+}
+
+func (t *badgerDBTxn) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	if t.closed {
+		return nil, errTxnClosed
+	}
+	return badgerGet(t.txn, key)
+}
+
+func (t *badgerDBTxn) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	if t.closed {
+		return false, errTxnClosed
+	}
+	return badgerHas(t.txn, key)
+}
+
+func (t *badgerDBTxn) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if t.closed {
+		return errTxnClosed
+	}
+	return t.txn.Set(key, value)
+}
+
+func (t *badgerDBTxn) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if t.closed {
+		return errTxnClosed
+	}
+	return t.txn.Delete(key)
+}
+
+func (t *badgerDBTxn) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	if t.closed {
+		return nil, errTxnClosed
+	}
+	return newBadgerDBIterator(t.txn, start, end, badger.DefaultIteratorOptions, false), nil
+}
+
+func (t *badgerDBTxn) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	if t.closed {
+		return nil, errTxnClosed
+	}
+	opts := badger.DefaultIteratorOptions
+	opts.Reverse = true
+	return newBadgerDBIterator(t.txn, end, start, opts, false), nil
+}
+
+func (t *badgerDBTxn) Commit() error {
+	if t.closed {
+		return errTxnClosed
+	}
+	t.closed = true
+	err := t.txn.Commit()
+	if errors.Is(err, badger.ErrConflict) {
+		return &ConflictError{}
+	}
+	return err
+}
+
+func (t *badgerDBTxn) Discard() error {
+	t.closed = true
+	t.txn.Discard()
+	return nil
+}
+
 type badgerDBIterator struct {
 	reverse    bool
+	keysOnly   bool
 	start, end []byte
 
-	txn  *badger.Txn
-	iter *badger.Iterator
+	txn     *badger.Txn
+	ownsTxn bool
+	iter    *badger.Iterator
 
 	lastErr error
 }
 
 func (i *badgerDBIterator) Close() error {
 	i.iter.Close()
-	i.txn.Discard()
+	if i.ownsTxn {
+		i.txn.Discard()
+	}
 	return nil
 }
 
-func (i *badgerDBIterator) Domain() (start, end []byte) { return i.start, i.end }
-func (i *badgerDBIterator) Error() error                { return i.lastErr }
+func (i *badgerDBIterator) Domain() (start, end []byte) {
+	if i.reverse { // start and end are swapped for reverse iterators
+		return i.end, i.start
+	}
+	return i.start, i.end
+}
+
+func (i *badgerDBIterator) Error() error { return i.lastErr }
 
 func (i *badgerDBIterator) Next() {
 	if !i.Valid() {
@@ -260,6 +722,23 @@ func (i *badgerDBIterator) Next() {
 	i.iter.Next()
 }
 
+func (i *badgerDBIterator) Seek(key []byte) {
+	if !i.reverse {
+		start, _ := seekDomain(i.start, i.end, key, false)
+		i.iter.Seek(start)
+		return
+	}
+	// For reverse iterators, start is the exclusive end of the domain.
+	if i.start != nil && bytes.Compare(key, i.start) >= 0 {
+		i.iter.Seek(i.start)
+		if i.iter.Valid() && bytes.Equal(i.iter.Item().Key(), i.start) {
+			i.iter.Next()
+		}
+		return
+	}
+	i.iter.Seek(key)
+}
+
 func (i *badgerDBIterator) Valid() bool {
 	if !i.iter.Valid() {
 		return false
@@ -287,6 +766,9 @@ func (i *badgerDBIterator) Value() []byte {
 	if !i.Valid() {
 		panic("iterator is invalid")
 	}
+	if i.keysOnly {
+		return nil
+	}
 	val, err := i.iter.Item().ValueCopy(nil)
 	if err != nil {
 		i.lastErr = err
@@ -4,18 +4,71 @@
 package db
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"go.etcd.io/bbolt"
 )
 
 var bucket = []byte("tm")
 
+// Default time to wait for the file lock when opening a database read-only via NewDBWithOptions,
+// e.g. while another process has it open for writing.
+const boltDBReadOnlyTimeout = time.Second
+
 func init() {
-	registerDBCreator(BoltDBBackend, NewBoltDB, false)
+	dbCreator := func(name string, dir string, opts Options) (DB, error) {
+		o, err := boltDBOptions(opts)
+		if err != nil {
+			return nil, err
+		}
+		return NewBoltDBWithOpts(name, dir, o)
+	}
+	registerDBCreator(BoltDBBackend, dbCreator, false)
+}
+
+// boltDBOptions converts options passed to NewDBWithOptions. Supported options are:
+//
+//   - no_sync: whether to skip syncing writes to disk, including sync writes
+//   - no_freelist_sync: whether to skip syncing the freelist to disk
+//   - initial_mmap_size: the initial size of the memory map, in bytes
+//   - read_only: whether to open an existing database read-only, which waits until no other
+//     process has it open for writing, or fails once timeout_ms has passed
+//   - timeout_ms: how long to wait for the file lock in milliseconds, or 0 to wait indefinitely;
+//     defaults to 1000 when read_only is set, so that opening a live node's database doesn't hang
+//
+// Unset options default to bbolt.DefaultOptions.
+func boltDBOptions(opts Options) (*bbolt.Options, error) {
+	var (
+		o   = *bbolt.DefaultOptions
+		err error
+	)
+	if o.NoSync, err = optBool(opts, "no_sync", o.NoSync); err != nil {
+		return nil, err
+	}
+	if o.NoFreelistSync, err = optBool(opts, "no_freelist_sync", o.NoFreelistSync); err != nil {
+		return nil, err
+	}
+	if o.InitialMmapSize, err = optInt(opts, "initial_mmap_size", o.InitialMmapSize); err != nil {
+		return nil, err
+	}
+	if o.ReadOnly, err = optBool(opts, "read_only", o.ReadOnly); err != nil {
+		return nil, err
+	}
+	if o.ReadOnly && o.Timeout == 0 {
+		o.Timeout = boltDBReadOnlyTimeout
+	}
+	timeout, err := optInt(opts, "timeout_ms", int(o.Timeout/time.Millisecond))
+	if err != nil {
+		return nil, err
+	}
+	o.Timeout = time.Duration(timeout) * time.Millisecond
+	return &o, nil
 }
 
 // BoltDB is a wrapper around etcd's fork of bolt (https://github.com/etcd-io/bbolt).
@@ -26,7 +79,8 @@ func init() {
 // A single bucket ([]byte("tm")) is used per a database instance. This could
 // lead to performance issues when/if there will be lots of keys.
 type BoltDB struct {
-	db *bbolt.DB
+	db     *bbolt.DB
+	txnMtx sync.Mutex
 }
 
 var _ DB = (*BoltDB)(nil)
@@ -36,25 +90,35 @@ func NewBoltDB(name, dir string) (DB, error) {
 	return NewBoltDBWithOpts(name, dir, bbolt.DefaultOptions)
 }
 
-// NewBoltDBWithOpts allows you to supply *bbolt.Options. ReadOnly: true is not
-// supported because NewBoltDBWithOpts creates a global bucket.
+// NewBoltDBWithOpts allows you to supply *bbolt.Options. With ReadOnly: true, the database must
+// already exist, including its global bucket, and writes fail with bbolt.ErrDatabaseReadOnly.
+// Opening a database blocks while another process has it open for writing, unless opts.Timeout is
+// set, in which case it fails with bbolt.ErrTimeout once the timeout has passed.
 func NewBoltDBWithOpts(name string, dir string, opts *bbolt.Options) (DB, error) {
-	if opts.ReadOnly {
-		return nil, errors.New("ReadOnly: true is not supported")
-	}
-
 	dbPath := filepath.Join(dir, name+".db")
 	db, err := bbolt.Open(dbPath, os.ModePerm, opts)
-	if err != nil {
+	if errors.Is(err, bbolt.ErrTimeout) {
+		return nil, fmt.Errorf("failed to lock %v, it may be open for writing: %w", dbPath, err)
+	} else if err != nil {
 		return nil, err
 	}
 
-	// create a global bucket
-	err = db.Update(func(tx *bbolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(bucket)
-		return err
-	})
+	if opts.ReadOnly {
+		err = db.View(func(tx *bbolt.Tx) error {
+			if tx.Bucket(bucket) == nil {
+				return errors.New("bucket not found in read-only database")
+			}
+			return nil
+		})
+	} else {
+		// create a global bucket
+		err = db.Update(func(tx *bbolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(bucket)
+			return err
+		})
+	}
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
@@ -88,6 +152,29 @@ func (bdb *BoltDB) Has(key []byte) (bool, error) {
 	return bytes != nil, nil
 }
 
+// MultiGet implements DB. All keys are read within a single transaction.
+func (bdb *BoltDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+	}
+	values := make([][]byte, len(keys))
+	err := bdb.db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bucket)
+		for i, key := range keys {
+			if v := b.Get(key); v != nil {
+				values[i] = append([]byte{}, v...)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
 // Set implements DB.
 func (bdb *BoltDB) Set(key, value []byte) error {
 	if len(key) == 0 {
@@ -130,6 +217,37 @@ func (bdb *BoltDB) DeleteSync(key []byte) error {
 	return bdb.Delete(key)
 }
 
+// DeleteRange implements DB.
+func (bdb *BoltDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	return bdb.db.Update(func(tx *bbolt.Tx) error {
+		return boltDeleteRange(tx.Bucket(bucket), start, end)
+	})
+}
+
+// boltDeleteRange deletes a range of keys from the bucket using a cursor.
+func boltDeleteRange(b *bbolt.Bucket, start, end []byte) error {
+	c := b.Cursor()
+	var k []byte
+	if start == nil {
+		k, _ = c.First()
+	} else {
+		k, _ = c.Seek(start)
+	}
+	for k != nil && (end == nil || bytes.Compare(k, end) < 0) {
+		// Advancing a bolt cursor after a delete may skip items, so we seek past the deleted key
+		// instead.
+		key := append([]byte{}, k...)
+		if err := c.Delete(); err != nil {
+			return err
+		}
+		k, _ = c.Seek(key)
+	}
+	return nil
+}
+
 // Close implements DB.
 func (bdb *BoltDB) Close() error {
 	return bdb.db.Close()
@@ -172,33 +290,107 @@ func (bdb *BoltDB) Stats() map[string]string {
 	return m
 }
 
+// TypedStats implements DB. Bolt iterators only hold read transactions open while reading a page,
+// so open iterators are not reported.
+func (bdb *BoltDB) TypedStats() DBStats {
+	stats := newDBStats()
+	_ = bdb.db.View(func(tx *bbolt.Tx) error {
+		stats.DiskSize = tx.Size()
+		return nil
+	})
+	return stats
+}
+
+// Capabilities implements DB.
+func (bdb *BoltDB) Capabilities() Capabilities {
+	return Capabilities{
+		AtomicBatch: true,
+		SyncWrites:  !bdb.db.NoSync,
+		Snapshot:    true,
+		Checkpoint:  true,
+	}
+}
+
 // NewBatch implements DB.
 func (bdb *BoltDB) NewBatch() Batch {
 	return newBoltDBBatch(bdb)
 }
 
-// WARNING: Any concurrent writes or reads will block until the iterator is
-// closed.
+// Snapshot implements DB. The snapshot holds a read-only transaction open until it is closed.
+//
+// WARNING: Writes that need to grow the database's memory map will block until the snapshot is
+// closed, so writing from the goroutine holding the snapshot may deadlock.
+func (bdb *BoltDB) Snapshot() (Snapshot, error) {
+	tx, err := bdb.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	return newBoltDBSnapshot(tx), nil
+}
+
+// NewTxn implements DB.
+//
+// To avoid holding a read transaction open (see Snapshot), the transaction reads from the live
+// database rather than a snapshot, so it does not observe the database as of its creation as
+// described by Txn. Values are fixed once read by Get or Has, and are checked for conflicts on
+// commit as usual, but iterators see the database as of when they are created.
+func (bdb *BoltDB) NewTxn() (Txn, error) {
+	return newSnapshotTxn(bdb, boltDBReader{db: bdb}, &bdb.txnMtx), nil
+}
+
+// Checkpoint implements DB. The database file is copied within a read transaction, which doesn't
+// block writers.
+func (bdb *BoltDB) Checkpoint(dir string) error {
+	if err := createCheckpointDir(dir); err != nil {
+		return err
+	}
+	return bdb.db.View(func(tx *bbolt.Tx) error {
+		return tx.CopyFile(filepath.Join(dir, filepath.Base(bdb.db.Path())), 0o600)
+	})
+}
+
+// Iterator implements DB.
+//
+// Bolt does not allow writes that grow its memory map while a read transaction is open, so rather
+// than iterating over a single snapshot, the iterator reads items in pages using separate read
+// transactions. Writes can therefore be made while iterating, but changes ahead of the iterator's
+// current page will be visible to it, so Capabilities.IteratorSnapshot is false. In particular, a
+// loop that writes keys ahead of the iterator may encounter its own writes, so callers must only
+// write to keys that the iterator has already passed, as documented for DB.Iterator.
 func (bdb *BoltDB) Iterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
-	tx, err := bdb.db.Begin(false)
-	if err != nil {
+	itr := newBoltDBIterator(bdb.db.View, start, end, IteratorOptions{})
+	if err := itr.Error(); err != nil {
 		return nil, err
 	}
-	return newBoltDBIterator(tx, start, end, false), nil
+	return itr, nil
 }
 
-// WARNING: Any concurrent writes or reads will block until the iterator is
-// closed.
+// ReverseIterator implements DB.
+// See Iterator for the consistency guarantees of the iterator.
 func (bdb *BoltDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
-	tx, err := bdb.db.Begin(false)
-	if err != nil {
+	itr := newBoltDBIterator(bdb.db.View, start, end, IteratorOptions{Reverse: true})
+	if err := itr.Error(); err != nil {
+		return nil, err
+	}
+	return itr, nil
+}
+
+// IteratorWithOptions implements DB. Key-only iterators don't copy values out of the memory map,
+// and PrefetchSize sets the number of items read per page.
+// See Iterator for the consistency guarantees of the iterator.
+func (bdb *BoltDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	itr := newBoltDBIterator(bdb.db.View, start, end, opts)
+	if err := itr.Error(); err != nil {
 		return nil, err
 	}
-	return newBoltDBIterator(tx, start, end, true), nil
+	return itr, nil
 }
@@ -7,8 +7,9 @@ import "go.etcd.io/bbolt"
 
 // boltDBBatch stores operations internally and dumps them to BoltDB on Write().
 type boltDBBatch struct {
-	db  *BoltDB
-	ops []operation
+	db   *BoltDB
+	ops  []operation
+	size int // size of the keys and values in ops
 }
 
 var _ Batch = (*boltDBBatch)(nil)
@@ -32,6 +33,7 @@ func (b *boltDBBatch) Set(key, value []byte) error {
 		return errBatchClosed
 	}
 	b.ops = append(b.ops, operation{opTypeSet, key, value})
+	b.size += len(key) + len(value)
 	return nil
 }
 
@@ -44,9 +46,42 @@ func (b *boltDBBatch) Delete(key []byte) error {
 		return errBatchClosed
 	}
 	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
+	b.size += len(key)
 	return nil
 }
 
+// DeleteRange implements Batch. The range is resolved to deletions of the keys in it when called.
+func (b *boltDBBatch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	deletes, err := deleteRangeOps(b.db, b.ops, start, end)
+	if err != nil {
+		return err
+	}
+	for _, op := range deletes {
+		b.ops = append(b.ops, op)
+		b.size += len(op.key)
+	}
+	return nil
+}
+
+// GetByteSize implements Batch.
+func (b *boltDBBatch) GetByteSize() (int, error) {
+	if b.ops == nil {
+		return 0, errBatchClosed
+	}
+	return b.size, nil
+}
+
+// Len implements Batch.
+func (b *boltDBBatch) Len() int {
+	return len(b.ops)
+}
+
 // Write implements Batch.
 func (b *boltDBBatch) Write() error {
 	if b.ops == nil {
@@ -83,5 +118,6 @@ func (b *boltDBBatch) WriteSync() error {
 // Close implements Batch.
 func (b *boltDBBatch) Close() error {
 	b.ops = nil
+	b.size = 0
 	return nil
 }
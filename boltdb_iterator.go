@@ -9,56 +9,130 @@ import (
 	"go.etcd.io/bbolt"
 )
 
+const (
+	// Default number of items read from the database per read transaction. Iterators don't hold a
+	// transaction open while the caller consumes items, since bolt writers that need to grow the
+	// memory map would block until it is closed.
+	boltDBIteratorPageSize = 1000
+)
+
 // boltDBIterator allows you to iterate on range of keys/values given some
-// start / end keys (nil & nil will result in doing full scan).
+// start / end keys (nil & nil will result in doing full scan). Items are read
+// in pages, each within its own read transaction obtained via view.
 type boltDBIterator struct {
-	tx *bbolt.Tx
-
-	itr   *bbolt.Cursor
+	view  func(func(*bbolt.Tx) error) error
 	start []byte
 	end   []byte
 
-	currentKey   []byte
-	currentValue []byte
+	keys   [][]byte
+	values [][]byte
+	pos    int
+
+	seekStart []byte // range that the first page is read from, as set by Seek
+	seekEnd   []byte
+	lastKey   []byte // last key read from the database, where the next page continues from
+	lastPage  bool   // whether there are no more pages after the current one
 
+	err       error
 	isInvalid bool
 	isReverse bool
+	keysOnly  bool
+	pageSize  int
 }
 
 var _ Iterator = (*boltDBIterator)(nil)
 
-// newBoltDBIterator creates a new boltDBIterator.
-func newBoltDBIterator(tx *bbolt.Tx, start, end []byte, isReverse bool) *boltDBIterator {
-	itr := tx.Bucket(bucket).Cursor()
-
-	var ck, cv []byte
-	if isReverse {
-		switch {
-		case end == nil:
-			ck, cv = itr.Last()
-		default:
-			_, _ = itr.Seek(end) // after key
-			ck, cv = itr.Prev()  // return to end key
+// newBoltDBIterator creates a new boltDBIterator, which reads from the transactions passed to
+// the view callback.
+func newBoltDBIterator(view func(func(*bbolt.Tx) error) error, start, end []byte, opts IteratorOptions) *boltDBIterator {
+	itr := &boltDBIterator{
+		view:      view,
+		start:     start,
+		end:       end,
+		seekStart: start,
+		seekEnd:   end,
+		isReverse: opts.Reverse,
+		keysOnly:  opts.KeysOnly,
+		pageSize:  boltDBIteratorPageSize,
+		isInvalid: false,
+	}
+	if opts.PrefetchSize > 0 {
+		itr.pageSize = opts.PrefetchSize
+	}
+	itr.loadPage()
+	return itr
+}
+
+// Seek implements Iterator.
+func (itr *boltDBIterator) Seek(key []byte) {
+	itr.seekStart, itr.seekEnd = seekDomain(itr.start, itr.end, key, itr.isReverse)
+	itr.lastKey = nil
+	itr.isInvalid = false
+	itr.loadPage()
+}
+
+// loadPage reads the next page of items into the iterator.
+func (itr *boltDBIterator) loadPage() {
+	itr.keys = itr.keys[:0]
+	itr.values = itr.values[:0]
+	itr.pos = 0
+	itr.err = itr.view(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		k, v := itr.seek(c)
+		for ; k != nil && len(itr.keys) < itr.pageSize; k, v = itr.step(c) {
+			if itr.isReverse && itr.start != nil && bytes.Compare(k, itr.start) < 0 {
+				break
+			}
+			if !itr.isReverse && itr.end != nil && bytes.Compare(itr.end, k) <= 0 {
+				break
+			}
+			itr.keys = append(itr.keys, append([]byte{}, k...))
+			if itr.keysOnly {
+				itr.values = append(itr.values, nil)
+			} else {
+				itr.values = append(itr.values, append([]byte{}, v...))
+			}
 		}
-	} else {
-		switch {
-		case start == nil:
-			ck, cv = itr.First()
-		default:
-			ck, cv = itr.Seek(start)
+		return nil
+	})
+	itr.lastPage = len(itr.keys) < itr.pageSize
+	if len(itr.keys) > 0 {
+		itr.lastKey = itr.keys[len(itr.keys)-1]
+	}
+}
+
+// seek positions the cursor at the first item of the next page.
+func (itr *boltDBIterator) seek(c *bbolt.Cursor) (key, value []byte) {
+	switch {
+	case itr.lastKey != nil && itr.isReverse:
+		_, _ = c.Seek(itr.lastKey) // last key or after it, if it has since been deleted
+		return c.Prev()
+	case itr.lastKey != nil:
+		key, value = c.Seek(itr.lastKey)
+		if bytes.Equal(key, itr.lastKey) {
+			return c.Next()
 		}
+		return key, value
+	case itr.isReverse && itr.seekEnd == nil:
+		return c.Last()
+	case itr.isReverse:
+		if key, _ = c.Seek(itr.seekEnd); key == nil { // after key
+			return c.Last()
+		}
+		return c.Prev() // return to end key
+	case itr.seekStart == nil:
+		return c.First()
+	default:
+		return c.Seek(itr.seekStart)
 	}
+}
 
-	return &boltDBIterator{
-		tx:           tx,
-		itr:          itr,
-		start:        start,
-		end:          end,
-		currentKey:   ck,
-		currentValue: cv,
-		isReverse:    isReverse,
-		isInvalid:    false,
+// step moves the cursor to the next item in the order of iteration.
+func (itr *boltDBIterator) step(c *bbolt.Cursor) (key, value []byte) {
+	if itr.isReverse {
+		return c.Prev()
 	}
+	return c.Next()
 }
 
 // Domain implements Iterator.
@@ -77,24 +151,12 @@ func (itr *boltDBIterator) Valid() bool {
 		return false
 	}
 
-	// iterated to the end of the cursor
-	if itr.currentKey == nil {
+	// iterated to the end of the domain
+	if itr.pos >= len(itr.keys) {
 		itr.isInvalid = true
 		return false
 	}
 
-	if itr.isReverse {
-		if itr.start != nil && bytes.Compare(itr.currentKey, itr.start) < 0 {
-			itr.isInvalid = true
-			return false
-		}
-	} else {
-		if itr.end != nil && bytes.Compare(itr.end, itr.currentKey) <= 0 {
-			itr.isInvalid = true
-			return false
-		}
-	}
-
 	// Valid
 	return true
 }
@@ -102,37 +164,34 @@ func (itr *boltDBIterator) Valid() bool {
 // Next implements Iterator.
 func (itr *boltDBIterator) Next() {
 	itr.assertIsValid()
-	if itr.isReverse {
-		itr.currentKey, itr.currentValue = itr.itr.Prev()
-	} else {
-		itr.currentKey, itr.currentValue = itr.itr.Next()
+	itr.pos++
+	if itr.pos >= len(itr.keys) && !itr.lastPage {
+		itr.loadPage()
 	}
 }
 
 // Key implements Iterator.
 func (itr *boltDBIterator) Key() []byte {
 	itr.assertIsValid()
-	return append([]byte{}, itr.currentKey...)
+	return itr.keys[itr.pos]
 }
 
 // Value implements Iterator.
 func (itr *boltDBIterator) Value() []byte {
 	itr.assertIsValid()
-	var value []byte
-	if itr.currentValue != nil {
-		value = append([]byte{}, itr.currentValue...)
-	}
-	return value
+	return itr.values[itr.pos]
 }
 
 // Error implements Iterator.
 func (itr *boltDBIterator) Error() error {
-	return nil
+	return itr.err
 }
 
 // Close implements Iterator.
 func (itr *boltDBIterator) Close() error {
-	return itr.tx.Rollback()
+	itr.keys = nil
+	itr.values = nil
+	return nil
 }
 
 func (itr *boltDBIterator) assertIsValid() {
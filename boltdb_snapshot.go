@@ -0,0 +1,99 @@
+//go:build boltdb
+// +build boltdb
+
+package db
+
+import "go.etcd.io/bbolt"
+
+// boltDBSnapshot is a BoltDB snapshot backed by a read-only transaction.
+type boltDBSnapshot struct {
+	tx *bbolt.Tx
+}
+
+var _ Snapshot = (*boltDBSnapshot)(nil)
+
+func newBoltDBSnapshot(tx *bbolt.Tx) *boltDBSnapshot {
+	return &boltDBSnapshot{
+		tx: tx,
+	}
+}
+
+// Get implements Snapshot.
+func (s *boltDBSnapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	var value []byte
+	if v := s.tx.Bucket(bucket).Get(key); v != nil {
+		value = append([]byte{}, v...)
+	}
+	return value, nil
+}
+
+// Has implements Snapshot.
+func (s *boltDBSnapshot) Has(key []byte) (bool, error) {
+	bytes, err := s.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return bytes != nil, nil
+}
+
+// Iterator implements Snapshot.
+func (s *boltDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return newBoltDBIterator(s.view, start, end, IteratorOptions{}), nil
+}
+
+// ReverseIterator implements Snapshot.
+func (s *boltDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return newBoltDBIterator(s.view, start, end, IteratorOptions{Reverse: true}), nil
+}
+
+// view runs fn within the snapshot's transaction.
+func (s *boltDBSnapshot) view(fn func(*bbolt.Tx) error) error {
+	return fn(s.tx)
+}
+
+// Close implements Snapshot.
+func (s *boltDBSnapshot) Close() error {
+	return s.tx.Rollback()
+}
+
+// boltDBReader reads from the live database through the Snapshot interface, for use where holding
+// a read transaction open is not possible. It does not provide point-in-time reads.
+type boltDBReader struct {
+	db *BoltDB
+}
+
+var _ Snapshot = boltDBReader{}
+
+// Get implements Snapshot.
+func (r boltDBReader) Get(key []byte) ([]byte, error) {
+	return r.db.Get(key)
+}
+
+// Has implements Snapshot.
+func (r boltDBReader) Has(key []byte) (bool, error) {
+	return r.db.Has(key)
+}
+
+// Iterator implements Snapshot.
+func (r boltDBReader) Iterator(start, end []byte) (Iterator, error) {
+	return r.db.Iterator(start, end)
+}
+
+// ReverseIterator implements Snapshot.
+func (r boltDBReader) ReverseIterator(start, end []byte) (Iterator, error) {
+	return r.db.ReverseIterator(start, end)
+}
+
+// Close implements Snapshot. It does not close the database.
+func (r boltDBReader) Close() error {
+	return nil
+}
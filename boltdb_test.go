@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
 )
 
 func TestBoltDBNewBoltDB(t *testing.T) {
@@ -32,6 +33,38 @@ func TestWithBoltDB(t *testing.T) {
 	t.Run("BoltDB", func(t *testing.T) { Run(t, db) })
 }
 
+func TestBoltDBIteratorPages(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	defer cleanupDBDir(dir, name)
+
+	db, err := NewBoltDB(name, dir)
+	require.NoError(t, err)
+	defer db.Close()
+
+	const numItems = 2*boltDBIteratorPageSize + 10
+	batch := db.NewBatch()
+	for i := int64(0); i < numItems; i++ {
+		require.NoError(t, batch.Set(int642Bytes(i), []byte{}))
+	}
+	require.NoError(t, batch.Write())
+
+	var expect []int64
+	for i := int64(5); i < numItems-5; i++ {
+		expect = append(expect, i)
+	}
+	itr, err := db.Iterator(int642Bytes(5), int642Bytes(numItems-5))
+	require.NoError(t, err)
+	verifyIterator(t, itr, expect, "forward iterator across pages")
+
+	for i, j := 0, len(expect)-1; i < j; i, j = i+1, j-1 {
+		expect[i], expect[j] = expect[j], expect[i]
+	}
+	ritr, err := db.ReverseIterator(int642Bytes(5), int642Bytes(numItems-5))
+	require.NoError(t, err)
+	verifyIterator(t, ritr, expect, "reverse iterator across pages")
+}
+
 func BenchmarkBoltDBRandomReadsWrites(b *testing.B) {
 	name := fmt.Sprintf("test_%x", randStr(12))
 	db, err := NewBoltDB(name, "")
@@ -45,3 +78,25 @@ func BenchmarkBoltDBRandomReadsWrites(b *testing.B) {
 
 	benchmarkRandomReadsWrites(b, db)
 }
+
+func TestBoltDBReadOnlyTimeout(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	defer cleanupDBDir(dir, name)
+
+	db, err := NewBoltDB(name, dir)
+	require.NoError(t, err)
+
+	// The database is locked for writing, so opening it read-only fails rather than hanging.
+	_, err = NewDBWithOptions(name, BoltDBBackend, dir, OptionsMap{"read_only": true, "timeout_ms": 10})
+	require.ErrorIs(t, err, bbolt.ErrTimeout)
+
+	opts, err := boltDBOptions(OptionsMap{"read_only": true})
+	require.NoError(t, err)
+	require.Equal(t, boltDBReadOnlyTimeout, opts.Timeout)
+
+	require.NoError(t, db.Close())
+	db, err = NewDBWithOptions(name, BoltDBBackend, dir, OptionsMap{"read_only": true, "timeout_ms": 10})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
@@ -0,0 +1,585 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/btree"
+)
+
+// BufferedDBOptions configures when a BufferedDB flushes its buffered writes. Thresholds less than
+// or equal to 0 are ignored, in which case writes are only flushed by Flush, synced writes and
+// Close.
+type BufferedDBOptions struct {
+	// MaxBytes flushes the buffer when the total size of the buffered keys and values reaches it.
+	MaxBytes int
+
+	// MaxKeys flushes the buffer when the number of buffered keys reaches it.
+	MaxKeys int
+
+	// FlushInterval flushes the buffer periodically, in a background goroutine.
+	FlushInterval time.Duration
+
+	// Sync flushes the buffer with Batch.WriteSync rather than Batch.Write.
+	Sync bool
+}
+
+// BufferedDB wraps a database, buffering writes in a MemDB overlay and writing them to the
+// underlying database in a single batch when flushed. Reads and iterators merge the buffered writes
+// with the underlying database, so the database behaves as if written directly, except that
+// buffered writes are lost if the process exits before they are flushed. SetSync, DeleteSync and
+// Batch.WriteSync flush all buffered writes before returning, as do transaction commits.
+//
+// Writes to the underlying database must not bypass the BufferedDB, since they may be overwritten
+// by older buffered writes. If a flush fails the writes remain buffered, and are retried on the
+// next flush.
+type BufferedDB struct {
+	db   DB
+	opts BufferedDBOptions
+
+	mtx     sync.RWMutex
+	writes  *MemDB // buffered writes, with nil values for deletions
+	size    int    // size of the buffered keys and values
+	flushes int
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+	doneCh    chan struct{}
+}
+
+var _ PrefixIteratorDB = (*BufferedDB)(nil)
+
+// NewBufferedDB wraps a database with a write buffer, flushed according to the given options.
+func NewBufferedDB(db DB, opts BufferedDBOptions) *BufferedDB {
+	bdb := &BufferedDB{
+		db:      db,
+		opts:    opts,
+		writes:  NewMemDB(),
+		closeCh: make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+	if opts.FlushInterval > 0 {
+		go bdb.flushPeriodically()
+	} else {
+		close(bdb.doneCh)
+	}
+	return bdb
+}
+
+// flushPeriodically flushes the buffer at the configured interval, until the database is closed.
+// Errors are ignored, since the writes remain buffered and are retried on the next flush.
+func (bdb *BufferedDB) flushPeriodically() {
+	defer close(bdb.doneCh)
+	ticker := time.NewTicker(bdb.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = bdb.Flush()
+		case <-bdb.closeCh:
+			return
+		}
+	}
+}
+
+// Flush writes all buffered writes to the underlying database in a single batch.
+func (bdb *BufferedDB) Flush() error {
+	bdb.mtx.Lock()
+	defer bdb.mtx.Unlock()
+	return bdb.flush(bdb.opts.Sync)
+}
+
+// flush writes the buffered writes to the underlying database. The mutex must be held.
+func (bdb *BufferedDB) flush(syncWrite bool) error {
+	if bdb.writes.btree.Len() == 0 {
+		return nil
+	}
+	batch := bdb.db.NewBatch()
+	defer batch.Close()
+
+	var err error
+	bdb.writes.btree.Ascend(func(i btree.Item) bool {
+		item := i.(item)
+		if item.value == nil {
+			err = batch.Delete(item.key)
+		} else {
+			err = batch.Set(item.key, item.value)
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	if syncWrite {
+		err = batch.WriteSync()
+	} else {
+		err = batch.Write()
+	}
+	if err != nil {
+		return err
+	}
+	bdb.writes = NewMemDB()
+	bdb.size = 0
+	bdb.flushes++
+	return nil
+}
+
+// apply buffers a set, or a deletion if value is nil. The mutex must be held.
+func (bdb *BufferedDB) apply(key, value []byte) {
+	bdb.writes.set(key, value)
+	bdb.size += len(key) + len(value)
+}
+
+// maybeFlush flushes the buffer if it has reached the thresholds, or with WriteSync if syncWrite
+// is given. The mutex must be held.
+func (bdb *BufferedDB) maybeFlush(syncWrite bool) error {
+	switch {
+	case syncWrite:
+		return bdb.flush(true)
+	case bdb.opts.MaxBytes > 0 && bdb.size >= bdb.opts.MaxBytes:
+		return bdb.flush(bdb.opts.Sync)
+	case bdb.opts.MaxKeys > 0 && bdb.writes.btree.Len() >= bdb.opts.MaxKeys:
+		return bdb.flush(bdb.opts.Sync)
+	default:
+		return nil
+	}
+}
+
+// get reads a key, from the buffer if it has been written. The mutex must be held.
+func (bdb *BufferedDB) get(key []byte) ([]byte, error) {
+	if i := bdb.writes.btree.Get(newKey(key)); i != nil {
+		return i.(item).value, nil
+	}
+	return bdb.db.Get(key)
+}
+
+// Get implements DB.
+func (bdb *BufferedDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	bdb.mtx.RLock()
+	defer bdb.mtx.RUnlock()
+	return bdb.get(key)
+}
+
+// Has implements DB.
+func (bdb *BufferedDB) Has(key []byte) (bool, error) {
+	value, err := bdb.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return value != nil, nil
+}
+
+// MultiGet implements DB.
+func (bdb *BufferedDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	bdb.mtx.RLock()
+	defer bdb.mtx.RUnlock()
+
+	values := make([][]byte, len(keys))
+	var (
+		missKeys    [][]byte
+		missIndexes []int
+	)
+	for i, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+		if it := bdb.writes.btree.Get(newKey(key)); it != nil {
+			values[i] = it.(item).value
+			continue
+		}
+		missKeys = append(missKeys, key)
+		missIndexes = append(missIndexes, i)
+	}
+	if len(missKeys) == 0 {
+		return values, nil
+	}
+	missValues, err := bdb.db.MultiGet(missKeys)
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range missValues {
+		values[missIndexes[i]] = value
+	}
+	return values, nil
+}
+
+// Set implements DB.
+func (bdb *BufferedDB) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	return bdb.write(key, value, false)
+}
+
+// SetSync implements DB.
+func (bdb *BufferedDB) SetSync(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	return bdb.write(key, value, true)
+}
+
+// Delete implements DB.
+func (bdb *BufferedDB) Delete(key []byte) error {
+	return bdb.write(key, nil, false)
+}
+
+// DeleteSync implements DB.
+func (bdb *BufferedDB) DeleteSync(key []byte) error {
+	return bdb.write(key, nil, true)
+}
+
+// write buffers a set, or a deletion if value is nil.
+func (bdb *BufferedDB) write(key, value []byte, syncWrite bool) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	bdb.mtx.Lock()
+	defer bdb.mtx.Unlock()
+	bdb.apply(key, value)
+	return bdb.maybeFlush(syncWrite)
+}
+
+// DeleteRange implements DB. The range is buffered as deletions of the keys currently in it.
+func (bdb *BufferedDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	bdb.mtx.Lock()
+	defer bdb.mtx.Unlock()
+	if err := bdb.deleteRange(start, end); err != nil {
+		return err
+	}
+	return bdb.maybeFlush(false)
+}
+
+// deleteRange buffers deletions of the keys currently in a range. The mutex must be held.
+func (bdb *BufferedDB) deleteRange(start, end []byte) error {
+	itr, err := bdb.newIterator(start, end, IteratorOptions{KeysOnly: true}, nil)
+	if err != nil {
+		return err
+	}
+	var keys [][]byte
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, itr.Key())
+	}
+	if err = itr.Error(); err != nil {
+		itr.Close()
+		return err
+	}
+	if err = itr.Close(); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		bdb.apply(key, nil)
+	}
+	return nil
+}
+
+// Iterator implements DB.
+func (bdb *BufferedDB) Iterator(start, end []byte) (Iterator, error) {
+	return bdb.IteratorWithOptions(start, end, IteratorOptions{})
+}
+
+// ReverseIterator implements DB.
+func (bdb *BufferedDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return bdb.IteratorWithOptions(start, end, IteratorOptions{Reverse: true})
+}
+
+// IteratorWithOptions implements DB.
+func (bdb *BufferedDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	bdb.mtx.RLock()
+	defer bdb.mtx.RUnlock()
+	return bdb.newIterator(start, end, opts, nil)
+}
+
+// IteratePrefix implements PrefixIteratorDB.
+func (bdb *BufferedDB) IteratePrefix(prefix []byte) (Iterator, error) {
+	bdb.mtx.RLock()
+	defer bdb.mtx.RUnlock()
+	start, end := prefixRange(prefix)
+	return bdb.newIterator(start, end, IteratorOptions{}, func() (Iterator, error) {
+		return IteratePrefix(bdb.db, prefix)
+	})
+}
+
+// ReverseIteratePrefix implements PrefixIteratorDB.
+func (bdb *BufferedDB) ReverseIteratePrefix(prefix []byte) (Iterator, error) {
+	bdb.mtx.RLock()
+	defer bdb.mtx.RUnlock()
+	start, end := prefixRange(prefix)
+	return bdb.newIterator(start, end, IteratorOptions{Reverse: true}, func() (Iterator, error) {
+		return ReverseIteratePrefix(bdb.db, prefix)
+	})
+}
+
+// newIterator creates an iterator merging the buffered writes with an iterator over the underlying
+// database, which is created by parentFn if given. The mutex must be held.
+func (bdb *BufferedDB) newIterator(
+	start, end []byte, opts IteratorOptions, parentFn func() (Iterator, error),
+) (Iterator, error) {
+	if parentFn == nil {
+		parentFn = func() (Iterator, error) {
+			return bdb.db.IteratorWithOptions(start, end, opts)
+		}
+	}
+	parent, err := parentFn()
+	if err != nil {
+		return nil, err
+	}
+	var itr Iterator = newMergeIterator(parent,
+		newMemDBIterator(bdb.writes, start, end, opts.Reverse), opts.Reverse)
+	if opts.KeysOnly {
+		itr = keysOnlyIterator{itr}
+	}
+	return itr, nil
+}
+
+// Close implements DB. It flushes the buffered writes before closing the underlying database,
+// which is closed even if the flush fails, in which case the buffered writes are lost.
+func (bdb *BufferedDB) Close() error {
+	bdb.closeOnce.Do(func() {
+		close(bdb.closeCh)
+	})
+	<-bdb.doneCh
+	flushErr := bdb.Flush()
+	closeErr := bdb.db.Close()
+	switch {
+	case flushErr != nil && closeErr != nil:
+		return fmt.Errorf("failed to flush buffered writes: %w (and failed to close database: %v)",
+			flushErr, closeErr)
+	case flushErr != nil:
+		return fmt.Errorf("failed to flush buffered writes: %w", flushErr)
+	default:
+		return closeErr
+	}
+}
+
+// NewBatch implements DB.
+func (bdb *BufferedDB) NewBatch() Batch {
+	return &bufferedDBBatch{bdb: bdb, ops: []operation{}}
+}
+
+// Print implements DB.
+func (bdb *BufferedDB) Print() error {
+	itr, err := bdb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+	return itr.Error()
+}
+
+// Stats implements DB. Besides the underlying database's statistics, it reports the number of
+// buffered keys, their size in bytes including values, and the number of flushes.
+func (bdb *BufferedDB) Stats() map[string]string {
+	stats := bdb.db.Stats()
+	if stats == nil {
+		stats = make(map[string]string)
+	}
+
+	bdb.mtx.RLock()
+	defer bdb.mtx.RUnlock()
+	stats["buffer.keys"] = fmt.Sprintf("%d", bdb.writes.btree.Len())
+	stats["buffer.size"] = fmt.Sprintf("%d", bdb.size)
+	stats["buffer.flushes"] = fmt.Sprintf("%d", bdb.flushes)
+	return stats
+}
+
+// TypedStats implements DB. It reports the underlying database's statistics, which don't include
+// buffered writes.
+func (bdb *BufferedDB) TypedStats() DBStats {
+	return bdb.db.TypedStats()
+}
+
+// Capabilities implements DB.
+func (bdb *BufferedDB) Capabilities() Capabilities {
+	caps := bdb.db.Capabilities()
+	caps.RangeDelete = false
+	return caps
+}
+
+// Snapshot implements DB. The buffered writes are flushed first, to include them in the snapshot.
+func (bdb *BufferedDB) Snapshot() (Snapshot, error) {
+	bdb.mtx.Lock()
+	defer bdb.mtx.Unlock()
+	if err := bdb.flush(bdb.opts.Sync); err != nil {
+		return nil, err
+	}
+	return bdb.db.Snapshot()
+}
+
+// NewTxn implements DB. The buffered writes are flushed first, to include them in the transaction's
+// snapshot, and again when committing, such that the commit is ordered after them.
+func (bdb *BufferedDB) NewTxn() (Txn, error) {
+	bdb.mtx.Lock()
+	defer bdb.mtx.Unlock()
+	if err := bdb.flush(bdb.opts.Sync); err != nil {
+		return nil, err
+	}
+	txn, err := bdb.db.NewTxn()
+	if err != nil {
+		return nil, err
+	}
+	return &bufferedDBTxn{Txn: txn, bdb: bdb}, nil
+}
+
+// Checkpoint implements DB. The buffered writes are flushed first, to include them in the
+// checkpoint.
+func (bdb *BufferedDB) Checkpoint(dir string) error {
+	bdb.mtx.Lock()
+	defer bdb.mtx.Unlock()
+	if err := bdb.flush(bdb.opts.Sync); err != nil {
+		return err
+	}
+	return bdb.db.Checkpoint(dir)
+}
+
+// bufferedDBTxn flushes the buffered writes before committing a transaction.
+type bufferedDBTxn struct {
+	Txn
+	bdb *BufferedDB
+}
+
+// Commit implements Txn.
+func (txn *bufferedDBTxn) Commit() error {
+	txn.bdb.mtx.Lock()
+	defer txn.bdb.mtx.Unlock()
+	if err := txn.bdb.flush(txn.bdb.opts.Sync); err != nil {
+		return err
+	}
+	return txn.Txn.Commit()
+}
+
+// bufferedDBBatch is a batch which is applied to the buffer of a BufferedDB atomically when written.
+type bufferedDBBatch struct {
+	bdb  *BufferedDB
+	ops  []operation
+	size int // size of the keys and values in ops
+}
+
+var _ Batch = (*bufferedDBBatch)(nil)
+
+// Set implements Batch.
+func (b *bufferedDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.ops = append(b.ops, operation{opTypeSet, key, value})
+	b.size += len(key) + len(value)
+	return nil
+}
+
+// Delete implements Batch.
+func (b *bufferedDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
+	b.size += len(key)
+	return nil
+}
+
+// DeleteRange implements Batch. The range is resolved to deletions of the keys in it when called.
+func (b *bufferedDBBatch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	deletes, err := deleteRangeOps(b.bdb, b.ops, start, end)
+	if err != nil {
+		return err
+	}
+	for _, op := range deletes {
+		b.ops = append(b.ops, op)
+		b.size += len(op.key)
+	}
+	return nil
+}
+
+// GetByteSize implements Batch.
+func (b *bufferedDBBatch) GetByteSize() (int, error) {
+	if b.ops == nil {
+		return 0, errBatchClosed
+	}
+	return b.size, nil
+}
+
+// Len implements Batch.
+func (b *bufferedDBBatch) Len() int {
+	return len(b.ops)
+}
+
+// Write implements Batch.
+func (b *bufferedDBBatch) Write() error {
+	return b.write(false)
+}
+
+// WriteSync implements Batch.
+func (b *bufferedDBBatch) WriteSync() error {
+	return b.write(true)
+}
+
+// write applies the batch to the buffer atomically.
+func (b *bufferedDBBatch) write(syncWrite bool) error {
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	bdb := b.bdb
+	bdb.mtx.Lock()
+	defer bdb.mtx.Unlock()
+
+	// Restore the buffer if an operation fails, leaving the batch unapplied.
+	writes, size := bdb.writes.btree.Clone(), bdb.size
+	for _, op := range b.ops {
+		var err error
+		switch op.opType {
+		case opTypeSet:
+			bdb.apply(op.key, op.value)
+		case opTypeDelete:
+			bdb.apply(op.key, nil)
+		default:
+			err = fmt.Errorf("unknown operation type %v (%v)", op.opType, op)
+		}
+		if err != nil {
+			bdb.writes.btree, bdb.size = writes, size
+			return err
+		}
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	b.ops = nil
+	return bdb.maybeFlush(syncWrite)
+}
+
+// Close implements Batch.
+func (b *bufferedDBBatch) Close() error {
+	b.ops = nil
+	return nil
+}
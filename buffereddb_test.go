@@ -0,0 +1,159 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferedDB(t *testing.T) {
+	source := NewMemDB()
+	require.NoError(t, source.Set([]byte("a"), []byte{1}))
+	require.NoError(t, source.Set([]byte("b"), []byte{2}))
+	require.NoError(t, source.Set([]byte("c"), []byte{3}))
+	bdb := NewBufferedDB(source, BufferedDBOptions{})
+
+	require.NoError(t, bdb.Set([]byte("b"), []byte{4}))
+	require.NoError(t, bdb.Delete([]byte("c")))
+	require.NoError(t, bdb.Set([]byte("d"), []byte{5}))
+	require.Equal(t, errKeyEmpty, bdb.Set(nil, []byte{1}))
+	require.Equal(t, errValueNil, bdb.Set([]byte("x"), nil))
+
+	// Writes are buffered, but visible through the BufferedDB.
+	value, err := source.Get([]byte("b"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{2}, value)
+	value, err = bdb.Get([]byte("b"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{4}, value)
+	ok, err := bdb.Has([]byte("c"))
+	require.NoError(t, err)
+	require.False(t, ok)
+	values, err := bdb.MultiGet([][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{{1}, {4}, nil, {5}}, values)
+
+	itr, err := bdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	assertIteratorItems(t, itr, []string{"a", "b", "d"}, [][]byte{{1}, {4}, {5}})
+	itr, err = bdb.ReverseIterator([]byte("b"), nil)
+	require.NoError(t, err)
+	assertIteratorItems(t, itr, []string{"d", "b"}, [][]byte{{5}, {4}})
+	itr, err = bdb.IteratorWithOptions(nil, nil, IteratorOptions{KeysOnly: true})
+	require.NoError(t, err)
+	assertIteratorItems(t, itr, []string{"a", "b", "d"}, [][]byte{nil, nil, nil})
+	itr, err = IteratePrefix(bdb, []byte("d"))
+	require.NoError(t, err)
+	assertIteratorItems(t, itr, []string{"d"}, [][]byte{{5}})
+
+	// Batches are applied to the buffer.
+	batch := bdb.NewBatch()
+	require.NoError(t, batch.Set([]byte("e"), []byte{6}))
+	require.NoError(t, batch.DeleteRange([]byte("a"), []byte("c")))
+	require.Equal(t, 3, batch.Len()) // the range deletion counts as one operation per deleted key
+	require.NoError(t, batch.Write())
+	require.Equal(t, errBatchClosed, batch.Write())
+	require.NoError(t, batch.Close())
+	itr, err = bdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	assertIteratorItems(t, itr, []string{"d", "e"}, [][]byte{{5}, {6}})
+
+	require.Equal(t, "5", bdb.Stats()["buffer.keys"])
+	require.Equal(t, "0", bdb.Stats()["buffer.flushes"])
+
+	// Flushing writes the buffer to the underlying database.
+	require.NoError(t, bdb.Flush())
+	require.Equal(t, "0", bdb.Stats()["buffer.keys"])
+	require.Equal(t, "1", bdb.Stats()["buffer.flushes"])
+	assertKeyValues(t, source, map[string][]byte{"d": {5}, "e": {6}})
+
+	// Synced writes and closing flush the buffer too.
+	require.NoError(t, bdb.Set([]byte("f"), []byte{7}))
+	require.NoError(t, bdb.DeleteSync([]byte("d")))
+	require.NoError(t, bdb.Set([]byte("g"), []byte{8}))
+	require.NoError(t, bdb.Close())
+	assertKeyValues(t, source, map[string][]byte{"e": {6}, "f": {7}, "g": {8}})
+}
+
+func TestBufferedDBThresholds(t *testing.T) {
+	source := NewMemDB()
+	bdb := NewBufferedDB(source, BufferedDBOptions{MaxKeys: 2, MaxBytes: 10})
+
+	require.NoError(t, bdb.Set([]byte("a"), []byte{1}))
+	require.Equal(t, 0, source.btree.Len())
+	require.NoError(t, bdb.Set([]byte("b"), []byte{2}))
+	require.Equal(t, 2, source.btree.Len())
+
+	require.NoError(t, bdb.Set([]byte("c"), make([]byte, 9)))
+	require.Equal(t, 3, source.btree.Len())
+
+	bdb = NewBufferedDB(source, BufferedDBOptions{FlushInterval: 10 * time.Millisecond})
+	require.NoError(t, bdb.Set([]byte("d"), []byte{4}))
+	require.Eventually(t, func() bool {
+		ok, err := source.Has([]byte("d"))
+		return err == nil && ok
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, bdb.Close())
+}
+
+func TestBufferedDBTxn(t *testing.T) {
+	source := NewMemDB()
+	bdb := NewBufferedDB(source, BufferedDBOptions{})
+
+	require.NoError(t, bdb.Set([]byte("a"), []byte{1}))
+	txn, err := bdb.NewTxn()
+	require.NoError(t, err)
+	value, err := txn.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, value)
+
+	// Writes buffered before the commit are flushed first, so the commit wins.
+	require.NoError(t, bdb.Set([]byte("b"), []byte{2}))
+	require.NoError(t, txn.Set([]byte("b"), []byte{3}))
+	require.NoError(t, txn.Commit())
+	require.NoError(t, txn.Discard())
+	require.NoError(t, bdb.Flush())
+	value, err = bdb.Get([]byte("b"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{3}, value)
+}
+
+// assertIteratorItems checks the remaining items of an iterator, and closes it.
+func assertIteratorItems(t *testing.T, itr Iterator, keys []string, values [][]byte) {
+	t.Helper()
+	var (
+		gotKeys   []string
+		gotValues [][]byte
+	)
+	for ; itr.Valid(); itr.Next() {
+		gotKeys = append(gotKeys, string(itr.Key()))
+		gotValues = append(gotValues, itr.Value())
+	}
+	require.NoError(t, itr.Error())
+	require.NoError(t, itr.Close())
+	require.Equal(t, keys, gotKeys)
+	require.Equal(t, values, gotValues)
+}
+
+// closeRecordingDB is a failingBatchDB which records whether it has been closed.
+type closeRecordingDB struct {
+	*failingBatchDB
+	closed bool
+}
+
+func (db *closeRecordingDB) Close() error {
+	db.closed = true
+	return db.failingBatchDB.Close()
+}
+
+func TestBufferedDBCloseFlushError(t *testing.T) {
+	source := &closeRecordingDB{failingBatchDB: &failingBatchDB{MemDB: NewMemDB()}}
+	bdb := NewBufferedDB(source, BufferedDBOptions{Sync: true})
+	require.NoError(t, bdb.Set([]byte("a"), []byte{1}))
+
+	// The underlying database is closed even though the buffered writes can't be flushed.
+	err := bdb.Close()
+	require.EqualError(t, err, "failed to flush buffered writes: write failed")
+	require.True(t, source.closed)
+}
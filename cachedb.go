@@ -0,0 +1,464 @@
+package db
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+// cacheEntry is an entry in the CachedDB LRU list.
+type cacheEntry struct {
+	key   string
+	value []byte // nil if the key does not exist
+}
+
+// size returns the number of bytes an entry counts against the cache capacity.
+func (e *cacheEntry) size() int {
+	return len(e.key) + len(e.value)
+}
+
+// cacheFill is a read of a key missing from the cache, whose value is cached once it is read unless
+// the key has been written in the meantime.
+type cacheFill struct {
+	key         string
+	invalidated bool
+}
+
+// CachedDB wraps a database with a read-through LRU cache for Get, Has and MultiGet, bounded by the
+// total size of the cached keys and values. Missing keys are cached too. Cached keys are
+// invalidated when written through the CachedDB, including via batches and transactions, so writes
+// to the underlying database must not bypass it. Iterators and snapshots read the underlying
+// database directly.
+//
+// To avoid caching stale values, reads that race with a write to the same key are not added to the
+// cache.
+type CachedDB struct {
+	db DB
+
+	mtx      sync.Mutex
+	capacity int
+	size     int
+	entries  map[string]*list.Element
+	lru      *list.List              // of *cacheEntry, most recently used first
+	fills    map[string][]*cacheFill // reads of missing keys in progress
+	hits     uint64
+	misses   uint64
+}
+
+var _ PrefixIteratorDB = (*CachedDB)(nil)
+
+// NewCachedDB wraps a database with a read-through cache of at most sizeBytes of keys and values.
+func NewCachedDB(db DB, sizeBytes int) *CachedDB {
+	return &CachedDB{
+		db:       db,
+		capacity: sizeBytes,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		fills:    make(map[string][]*cacheFill),
+	}
+}
+
+// lookup looks up a key in the cache. On misses, it starts a fill, which must be completed by
+// calling insert with the value read from the database, or abort.
+func (cdb *CachedDB) lookup(key []byte) (value []byte, ok bool, fill *cacheFill) {
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+
+	if elem, ok := cdb.entries[string(key)]; ok {
+		cdb.hits++
+		cdb.lru.MoveToFront(elem)
+		return elem.Value.(*cacheEntry).value, true, nil
+	}
+	cdb.misses++
+	fill = &cacheFill{key: string(key)}
+	cdb.fills[fill.key] = append(cdb.fills[fill.key], fill)
+	return nil, false, fill
+}
+
+// abort ends a fill without caching a value.
+func (cdb *CachedDB) abort(fill *cacheFill) {
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+	cdb.removeFill(fill)
+}
+
+// removeFill removes a fill from the fills in progress. The mutex must be held.
+func (cdb *CachedDB) removeFill(fill *cacheFill) {
+	fills := cdb.fills[fill.key]
+	for i, f := range fills {
+		if f == fill {
+			fills = append(fills[:i], fills[i+1:]...)
+			break
+		}
+	}
+	if len(fills) == 0 {
+		delete(cdb.fills, fill.key)
+	} else {
+		cdb.fills[fill.key] = fills
+	}
+}
+
+// insert ends a fill by caching the value read from the database, unless the key has been written
+// since the fill started.
+func (cdb *CachedDB) insert(fill *cacheFill, value []byte) {
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+
+	cdb.removeFill(fill)
+	if fill.invalidated {
+		return
+	}
+	entry := &cacheEntry{key: fill.key, value: value}
+	if entry.size() > cdb.capacity {
+		return
+	}
+	if elem, ok := cdb.entries[entry.key]; ok {
+		cdb.remove(elem)
+	}
+	cdb.entries[entry.key] = cdb.lru.PushFront(entry)
+	cdb.size += entry.size()
+	for cdb.size > cdb.capacity {
+		cdb.remove(cdb.lru.Back())
+	}
+}
+
+// remove removes an element from the cache. The mutex must be held.
+func (cdb *CachedDB) remove(elem *list.Element) {
+	entry := cdb.lru.Remove(elem).(*cacheEntry)
+	delete(cdb.entries, entry.key)
+	cdb.size -= entry.size()
+}
+
+// invalidate removes the given keys and ranges from the cache, and prevents fills of them that are
+// in progress from caching their values.
+func (cdb *CachedDB) invalidate(keys [][]byte, ranges [][2][]byte) {
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := cdb.entries[string(key)]; ok {
+			cdb.remove(elem)
+		}
+		for _, fill := range cdb.fills[string(key)] {
+			fill.invalidated = true
+		}
+	}
+	if len(ranges) == 0 {
+		return
+	}
+	for key, fills := range cdb.fills {
+		for _, r := range ranges {
+			if IsKeyInDomain([]byte(key), r[0], r[1]) {
+				for _, fill := range fills {
+					fill.invalidated = true
+				}
+				break
+			}
+		}
+	}
+	for elem := cdb.lru.Front(); elem != nil; {
+		next := elem.Next()
+		key := []byte(elem.Value.(*cacheEntry).key)
+		for _, r := range ranges {
+			if IsKeyInDomain(key, r[0], r[1]) {
+				cdb.remove(elem)
+				break
+			}
+		}
+		elem = next
+	}
+}
+
+// Get implements DB.
+func (cdb *CachedDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	value, ok, fill := cdb.lookup(key)
+	if ok {
+		return value, nil
+	}
+	value, err := cdb.db.Get(key)
+	if err != nil {
+		cdb.abort(fill)
+		return nil, err
+	}
+	cdb.insert(fill, value)
+	return value, nil
+}
+
+// Has implements DB. Keys missing from the cache are read with Get, to cache their values.
+func (cdb *CachedDB) Has(key []byte) (bool, error) {
+	value, err := cdb.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return value != nil, nil
+}
+
+// MultiGet implements DB.
+func (cdb *CachedDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+	}
+	values := make([][]byte, len(keys))
+	var (
+		missKeys    [][]byte
+		missIndexes []int
+		missFills   []*cacheFill
+	)
+	for i, key := range keys {
+		value, ok, fill := cdb.lookup(key)
+		if ok {
+			values[i] = value
+			continue
+		}
+		missKeys = append(missKeys, key)
+		missIndexes = append(missIndexes, i)
+		missFills = append(missFills, fill)
+	}
+	if len(missKeys) == 0 {
+		return values, nil
+	}
+	missValues, err := cdb.db.MultiGet(missKeys)
+	if err != nil {
+		for _, fill := range missFills {
+			cdb.abort(fill)
+		}
+		return nil, err
+	}
+	for i, value := range missValues {
+		values[missIndexes[i]] = value
+		cdb.insert(missFills[i], value)
+	}
+	return values, nil
+}
+
+// Set implements DB.
+func (cdb *CachedDB) Set(key, value []byte) error {
+	err := cdb.db.Set(key, value)
+	cdb.invalidate([][]byte{key}, nil)
+	return err
+}
+
+// SetSync implements DB.
+func (cdb *CachedDB) SetSync(key, value []byte) error {
+	err := cdb.db.SetSync(key, value)
+	cdb.invalidate([][]byte{key}, nil)
+	return err
+}
+
+// Delete implements DB.
+func (cdb *CachedDB) Delete(key []byte) error {
+	err := cdb.db.Delete(key)
+	cdb.invalidate([][]byte{key}, nil)
+	return err
+}
+
+// DeleteSync implements DB.
+func (cdb *CachedDB) DeleteSync(key []byte) error {
+	err := cdb.db.DeleteSync(key)
+	cdb.invalidate([][]byte{key}, nil)
+	return err
+}
+
+// DeleteRange implements DB.
+func (cdb *CachedDB) DeleteRange(start, end []byte) error {
+	err := cdb.db.DeleteRange(start, end)
+	cdb.invalidate(nil, [][2][]byte{{start, end}})
+	return err
+}
+
+// Iterator implements DB.
+func (cdb *CachedDB) Iterator(start, end []byte) (Iterator, error) {
+	return cdb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (cdb *CachedDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return cdb.db.ReverseIterator(start, end)
+}
+
+// IteratorWithOptions implements DB.
+func (cdb *CachedDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	return cdb.db.IteratorWithOptions(start, end, opts)
+}
+
+// IteratePrefix implements PrefixIteratorDB.
+func (cdb *CachedDB) IteratePrefix(prefix []byte) (Iterator, error) {
+	return IteratePrefix(cdb.db, prefix)
+}
+
+// ReverseIteratePrefix implements PrefixIteratorDB.
+func (cdb *CachedDB) ReverseIteratePrefix(prefix []byte) (Iterator, error) {
+	return ReverseIteratePrefix(cdb.db, prefix)
+}
+
+// Close implements DB.
+func (cdb *CachedDB) Close() error {
+	cdb.invalidate(nil, [][2][]byte{{nil, nil}})
+	return cdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (cdb *CachedDB) NewBatch() Batch {
+	return &cachedDBBatch{source: cdb.db.NewBatch(), cdb: cdb}
+}
+
+// Print implements DB.
+func (cdb *CachedDB) Print() error {
+	return cdb.db.Print()
+}
+
+// Stats implements DB. Besides the underlying database's statistics, it reports the cache's hits,
+// misses, hit rate, size in bytes and number of entries.
+func (cdb *CachedDB) Stats() map[string]string {
+	stats := cdb.db.Stats()
+	if stats == nil {
+		stats = make(map[string]string)
+	}
+
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+	hitRate := 0.0
+	if cdb.hits+cdb.misses > 0 {
+		hitRate = float64(cdb.hits) / float64(cdb.hits+cdb.misses)
+	}
+	stats["cache.hits"] = fmt.Sprintf("%d", cdb.hits)
+	stats["cache.misses"] = fmt.Sprintf("%d", cdb.misses)
+	stats["cache.hit_rate"] = fmt.Sprintf("%.4f", hitRate)
+	stats["cache.size"] = fmt.Sprintf("%d", cdb.size)
+	stats["cache.entries"] = fmt.Sprintf("%d", cdb.lru.Len())
+	return stats
+}
+
+// TypedStats implements DB.
+func (cdb *CachedDB) TypedStats() DBStats {
+	return cdb.db.TypedStats()
+}
+
+// Capabilities implements DB.
+func (cdb *CachedDB) Capabilities() Capabilities {
+	return cdb.db.Capabilities()
+}
+
+// Snapshot implements DB.
+func (cdb *CachedDB) Snapshot() (Snapshot, error) {
+	return cdb.db.Snapshot()
+}
+
+// NewTxn implements DB.
+func (cdb *CachedDB) NewTxn() (Txn, error) {
+	txn, err := cdb.db.NewTxn()
+	if err != nil {
+		return nil, err
+	}
+	return &cachedDBTxn{Txn: txn, cdb: cdb}, nil
+}
+
+// Checkpoint implements DB.
+func (cdb *CachedDB) Checkpoint(dir string) error {
+	return cdb.db.Checkpoint(dir)
+}
+
+// cachedDBBatch records the keys written by a batch, to invalidate them in the cache on write.
+type cachedDBBatch struct {
+	source Batch
+	cdb    *CachedDB
+	keys   [][]byte
+	ranges [][2][]byte
+}
+
+var _ Batch = (*cachedDBBatch)(nil)
+
+// Set implements Batch.
+func (b *cachedDBBatch) Set(key, value []byte) error {
+	if err := b.source.Set(key, value); err != nil {
+		return err
+	}
+	b.keys = append(b.keys, key)
+	return nil
+}
+
+// Delete implements Batch.
+func (b *cachedDBBatch) Delete(key []byte) error {
+	if err := b.source.Delete(key); err != nil {
+		return err
+	}
+	b.keys = append(b.keys, key)
+	return nil
+}
+
+// DeleteRange implements Batch.
+func (b *cachedDBBatch) DeleteRange(start, end []byte) error {
+	if err := b.source.DeleteRange(start, end); err != nil {
+		return err
+	}
+	b.ranges = append(b.ranges, [2][]byte{start, end})
+	return nil
+}
+
+// GetByteSize implements Batch.
+func (b *cachedDBBatch) GetByteSize() (int, error) {
+	return b.source.GetByteSize()
+}
+
+// Len implements Batch.
+func (b *cachedDBBatch) Len() int {
+	return b.source.Len()
+}
+
+// Write implements Batch.
+func (b *cachedDBBatch) Write() error {
+	err := b.source.Write()
+	b.cdb.invalidate(b.keys, b.ranges)
+	return err
+}
+
+// WriteSync implements Batch.
+func (b *cachedDBBatch) WriteSync() error {
+	err := b.source.WriteSync()
+	b.cdb.invalidate(b.keys, b.ranges)
+	return err
+}
+
+// Close implements Batch.
+func (b *cachedDBBatch) Close() error {
+	b.keys = nil
+	b.ranges = nil
+	return b.source.Close()
+}
+
+// cachedDBTxn records the keys written by a transaction, to invalidate them in the cache on commit.
+type cachedDBTxn struct {
+	Txn
+	cdb  *CachedDB
+	keys [][]byte
+}
+
+// Set implements Txn.
+func (txn *cachedDBTxn) Set(key, value []byte) error {
+	if err := txn.Txn.Set(key, value); err != nil {
+		return err
+	}
+	txn.keys = append(txn.keys, key)
+	return nil
+}
+
+// Delete implements Txn.
+func (txn *cachedDBTxn) Delete(key []byte) error {
+	if err := txn.Txn.Delete(key); err != nil {
+		return err
+	}
+	txn.keys = append(txn.keys, key)
+	return nil
+}
+
+// Commit implements Txn.
+func (txn *cachedDBTxn) Commit() error {
+	err := txn.Txn.Commit()
+	txn.cdb.invalidate(txn.keys, nil)
+	return err
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedDB(t *testing.T) {
+	source := NewMemDB()
+	require.NoError(t, source.Set([]byte("a"), []byte{1}))
+	require.NoError(t, source.Set([]byte("b"), []byte{2}))
+	cdb := NewCachedDB(source, 1024)
+
+	// Reads go through the cache, including for missing keys.
+	value, err := cdb.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, value)
+	ok, err := cdb.Has([]byte("x"))
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.NoError(t, source.Set([]byte("a"), []byte{9}))
+	require.NoError(t, source.Set([]byte("x"), []byte{9}))
+	value, err = cdb.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, value)
+	ok, err = cdb.Has([]byte("x"))
+	require.NoError(t, err)
+	require.False(t, ok)
+	values, err := cdb.MultiGet([][]byte{[]byte("a"), []byte("b"), []byte("x")})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{{1}, {2}, nil}, values)
+
+	_, err = cdb.Get(nil)
+	require.Equal(t, errKeyEmpty, err)
+
+	stats := cdb.Stats()
+	require.Equal(t, "memDB", stats["database.type"])
+	require.Equal(t, "4", stats["cache.hits"])
+	require.Equal(t, "3", stats["cache.misses"])
+	require.Equal(t, "0.5714", stats["cache.hit_rate"])
+	require.Equal(t, "3", stats["cache.entries"])
+	require.Equal(t, "5", stats["cache.size"])
+
+	// Writes through the CachedDB invalidate the cached keys.
+	require.NoError(t, cdb.Set([]byte("a"), []byte{3}))
+	require.NoError(t, cdb.Delete([]byte("x")))
+	values, err = cdb.MultiGet([][]byte{[]byte("a"), []byte("x")})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{{3}, nil}, values)
+
+	batch := cdb.NewBatch()
+	require.NoError(t, batch.Set([]byte("a"), []byte{4}))
+	require.NoError(t, batch.Set([]byte("x"), []byte{4}))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	values, err = cdb.MultiGet([][]byte{[]byte("a"), []byte("x")})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{{4}, {4}}, values)
+
+	txn, err := cdb.NewTxn()
+	require.NoError(t, err)
+	require.NoError(t, txn.Set([]byte("a"), []byte{5}))
+	require.NoError(t, txn.Commit())
+	require.NoError(t, txn.Discard())
+	value, err = cdb.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{5}, value)
+
+	_, err = cdb.Get([]byte("b"))
+	require.NoError(t, err)
+	require.NoError(t, cdb.DeleteRange([]byte("a"), []byte("c")))
+	values, err = cdb.MultiGet([][]byte{[]byte("a"), []byte("b")})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{nil, nil}, values)
+
+	// Iterators read the underlying database.
+	require.NoError(t, source.Set([]byte("b"), []byte{6}))
+	itr, err := cdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	require.True(t, itr.Valid())
+	require.Equal(t, []byte("b"), itr.Key())
+	require.Equal(t, []byte{6}, itr.Value())
+	require.NoError(t, itr.Close())
+
+	require.NoError(t, cdb.Close())
+}
+
+func TestCachedDBEviction(t *testing.T) {
+	source := NewMemDB()
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, source.Set([]byte(key), []byte{1, 2, 3}))
+	}
+	cdb := NewCachedDB(source, 8)
+
+	for _, key := range []string{"a", "b", "a", "c"} {
+		_, err := cdb.Get([]byte(key))
+		require.NoError(t, err)
+	}
+	// b was least recently used, and has been evicted.
+	require.Len(t, cdb.entries, 2)
+	require.Equal(t, 8, cdb.size)
+	require.Contains(t, cdb.entries, "a")
+	require.Contains(t, cdb.entries, "c")
+
+	// Entries larger than the cache are not cached.
+	require.NoError(t, source.Set([]byte("d"), make([]byte, 10)))
+	_, err := cdb.Get([]byte("d"))
+	require.NoError(t, err)
+	require.NotContains(t, cdb.entries, "d")
+	require.Len(t, cdb.entries, 2)
+
+	// Reads racing with writes to the same key are not cached, but writes to other keys don't
+	// prevent caching.
+	_, _, fillB := cdb.lookup([]byte("b"))
+	_, _, fillE := cdb.lookup([]byte("e"))
+	require.NoError(t, cdb.Set([]byte("b"), []byte{4}))
+	cdb.insert(fillB, []byte{1, 2, 3})
+	cdb.insert(fillE, nil)
+	require.NotContains(t, cdb.entries, "b")
+	require.Contains(t, cdb.entries, "e")
+
+	// Range deletions also prevent caching reads of keys in the range.
+	_, _, fillB = cdb.lookup([]byte("b"))
+	require.NoError(t, cdb.DeleteRange([]byte("a"), []byte("c")))
+	cdb.insert(fillB, nil)
+	require.NotContains(t, cdb.entries, "b")
+	require.Empty(t, cdb.fills)
+}
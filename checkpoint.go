@@ -0,0 +1,52 @@
+package db
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	// Size of the batches used to copy data into checkpoints, for backends which make them by
+	// copying a snapshot into a new database.
+	checkpointBatchSize = 16 << 20
+)
+
+// createCheckpointDir creates the directory for a checkpoint, which must not exist yet.
+func createCheckpointDir(dir string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("checkpoint directory %s already exists", dir)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return os.MkdirAll(dir, 0o755)
+}
+
+// copySnapshot copies the contents of a snapshot into a new database, and closes the database.
+func copySnapshot(snapshot Snapshot, db DB) error {
+	err := copySnapshotItems(snapshot, db)
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// copySnapshotItems copies the items of a snapshot into a database, in batches.
+func copySnapshotItems(snapshot Snapshot, db DB) error {
+	itr, err := snapshot.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+
+	batch := NewAutoFlushBatch(db, checkpointBatchSize, 0)
+	defer batch.Close()
+	for ; itr.Valid(); itr.Next() {
+		if err = batch.Set(itr.Key(), itr.Value()); err != nil {
+			return err
+		}
+	}
+	if err = itr.Error(); err != nil {
+		return err
+	}
+	return batch.WriteSync()
+}
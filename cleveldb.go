@@ -6,13 +6,14 @@ package db
 import (
 	"fmt"
 	"path/filepath"
+	"sync"
 
 	"github.com/jmhodges/levigo"
 )
 
 func init() {
-	dbCreator := func(name string, dir string) (DB, error) {
-		return NewCLevelDB(name, dir)
+	dbCreator := func(name string, dir string, opts Options) (DB, error) {
+		return newCLevelDB(name, dir, opts)
 	}
 	registerDBCreator(CLevelDBBackend, dbCreator, false)
 }
@@ -20,20 +21,60 @@ func init() {
 // CLevelDB uses the C LevelDB database via a Go wrapper.
 type CLevelDB struct {
 	db     *levigo.DB
+	name   string
 	ro     *levigo.ReadOptions
 	wo     *levigo.WriteOptions
 	woSync *levigo.WriteOptions
+	txnMtx sync.Mutex
 }
 
 var _ DB = (*CLevelDB)(nil)
 
 // NewCLevelDB creates a new CLevelDB.
 func NewCLevelDB(name string, dir string) (*CLevelDB, error) {
+	return newCLevelDB(name, dir, nil)
+}
+
+// newCLevelDB creates a new CLevelDB with options passed to NewDBWithOptions. Supported options
+// are:
+//
+//   - block_cache_size: the capacity of the block cache, in bytes (default 1 GB)
+//   - write_buffer_size: the size of the memtable, in bytes
+//   - max_open_files: the maximum number of open files
+//   - read_only: whether to open an existing database without writing to it. LevelDB has no
+//     read-only mode, so it fails while another process has the database open, and writes are only
+//     rejected by the NewReadOnlyDB wrapper applied by NewDBWithOptions
+//
+// Unset options default to the LevelDB defaults.
+func newCLevelDB(name string, dir string, o Options) (*CLevelDB, error) {
 	dbPath := filepath.Join(dir, name+".db")
 
+	blockCacheSize, err := optInt(o, "block_cache_size", 1<<30)
+	if err != nil {
+		return nil, err
+	}
+	writeBufferSize, err := optInt(o, "write_buffer_size", 0)
+	if err != nil {
+		return nil, err
+	}
+	maxOpenFiles, err := optInt(o, "max_open_files", 0)
+	if err != nil {
+		return nil, err
+	}
+	readOnly, err := optBool(o, "read_only", false)
+	if err != nil {
+		return nil, err
+	}
+
 	opts := levigo.NewOptions()
-	opts.SetCache(levigo.NewLRUCache(1 << 30))
-	opts.SetCreateIfMissing(true)
+	opts.SetCache(levigo.NewLRUCache(blockCacheSize))
+	opts.SetCreateIfMissing(!readOnly)
+	if writeBufferSize > 0 {
+		opts.SetWriteBufferSize(writeBufferSize)
+	}
+	if maxOpenFiles > 0 {
+		opts.SetMaxOpenFiles(maxOpenFiles)
+	}
 	db, err := levigo.Open(dbPath, opts)
 	if err != nil {
 		return nil, err
@@ -44,6 +85,7 @@ func NewCLevelDB(name string, dir string) (*CLevelDB, error) {
 	woSync.SetSync(true)
 	database := &CLevelDB{
 		db:     db,
+		name:   name,
 		ro:     ro,
 		wo:     wo,
 		woSync: woSync,
@@ -72,6 +114,11 @@ func (db *CLevelDB) Has(key []byte) (bool, error) {
 	return bytes != nil, nil
 }
 
+// MultiGet implements DB.
+func (db *CLevelDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return snapshotMultiGet(newCLevelDBSnapshot(db), keys)
+}
+
 // Set implements DB.
 func (db *CLevelDB) Set(key []byte, value []byte) error {
 	if len(key) == 0 {
@@ -122,6 +169,17 @@ func (db *CLevelDB) DeleteSync(key []byte) error {
 	return nil
 }
 
+// DeleteRange implements DB. LevelDB has no native range deletion, so the keys in the range are
+// deleted one by one in a single batch.
+func (db *CLevelDB) DeleteRange(start, end []byte) error {
+	batch := newCLevelDBBatch(db)
+	defer batch.Close()
+	if err := batch.DeleteRange(start, end); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
 // FIXME This should not be exposed
 func (db *CLevelDB) DB() *levigo.DB {
 	return db.db
@@ -168,11 +226,71 @@ func (db *CLevelDB) Stats() map[string]string {
 	return stats
 }
 
+// TypedStats implements DB. DiskSize is the approximate size of the table files, which doesn't
+// include the write-ahead log. LevelDB only reports its approximate memory usage, which includes
+// the block cache, so the memtable size is not reported.
+func (db *CLevelDB) TypedStats() DBStats {
+	stats := newDBStats()
+
+	// The key range must be bounded, so it ends after the last key.
+	itr := db.db.NewIterator(db.ro)
+	defer itr.Close()
+	itr.SeekToLast()
+	if !itr.Valid() {
+		if itr.GetError() == nil {
+			stats.DiskSize = 0
+		}
+		return stats
+	}
+	sizes := db.db.GetApproximateSizes([]levigo.Range{{Start: []byte{}, Limit: append(itr.Key(), 0)}})
+	stats.DiskSize = int64(sizes[0])
+	return stats
+}
+
+// Capabilities implements DB.
+func (db *CLevelDB) Capabilities() Capabilities {
+	return Capabilities{
+		AtomicBatch:      true,
+		SyncWrites:       true,
+		Snapshot:         true,
+		IteratorSnapshot: true,
+		Compaction:       true,
+	}
+}
+
 // NewBatch implements DB.
 func (db *CLevelDB) NewBatch() Batch {
 	return newCLevelDBBatch(db)
 }
 
+// Snapshot implements DB.
+func (db *CLevelDB) Snapshot() (Snapshot, error) {
+	return newCLevelDBSnapshot(db), nil
+}
+
+// NewTxn implements DB.
+func (db *CLevelDB) NewTxn() (Txn, error) {
+	return newSnapshotTxn(db, newCLevelDBSnapshot(db), &db.txnMtx), nil
+}
+
+// Checkpoint implements DB. LevelDB has no native checkpoints, so the contents of a snapshot are
+// copied into a new database, which is compacted but takes longer than copying files.
+func (db *CLevelDB) Checkpoint(dir string) error {
+	if err := createCheckpointDir(dir); err != nil {
+		return err
+	}
+	snapshot, err := db.Snapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+	target, err := NewCLevelDB(db.name, dir)
+	if err != nil {
+		return err
+	}
+	return copySnapshot(snapshot, target)
+}
+
 // Iterator implements DB.
 func (db *CLevelDB) Iterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
@@ -190,3 +308,20 @@ func (db *CLevelDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	itr := db.db.NewIterator(db.ro)
 	return newCLevelDBIterator(itr, start, end, true), nil
 }
+
+// IteratorWithOptions implements DB.
+func (db *CLevelDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	// LevelDB copies the read options into the iterator, so they can be released right away.
+	ro := levigo.NewReadOptions()
+	ro.SetFillCache(!opts.DontFillCache)
+	source := db.db.NewIterator(ro)
+	ro.Close()
+	var itr Iterator = newCLevelDBIterator(source, start, end, opts.Reverse)
+	if opts.KeysOnly {
+		itr = keysOnlyIterator{itr}
+	}
+	return itr, nil
+}
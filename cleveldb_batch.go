@@ -9,6 +9,9 @@ import "github.com/jmhodges/levigo"
 type cLevelDBBatch struct {
 	db    *CLevelDB
 	batch *levigo.WriteBatch
+	keys  [][]byte // keys set in the batch, since levigo can't replay batches for DeleteRange
+	count int      // levigo doesn't expose the number of operations or size of a batch
+	size  int
 }
 
 func newCLevelDBBatch(db *CLevelDB) *cLevelDBBatch {
@@ -30,6 +33,9 @@ func (b *cLevelDBBatch) Set(key, value []byte) error {
 		return errBatchClosed
 	}
 	b.batch.Put(key, value)
+	b.keys = append(b.keys, key)
+	b.count++
+	b.size += len(key) + len(value)
 	return nil
 }
 
@@ -41,10 +47,50 @@ func (b *cLevelDBBatch) Delete(key []byte) error {
 	if b.batch == nil {
 		return errBatchClosed
 	}
+	b.delete(key)
+	return nil
+}
+
+// delete adds a deletion to the batch.
+func (b *cLevelDBBatch) delete(key []byte) {
 	b.batch.Delete(key)
+	b.count++
+	b.size += len(key)
+}
+
+// DeleteRange implements Batch. The range is resolved to the keys present in the database and
+// the batch when it is called.
+func (b *cLevelDBBatch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if b.batch == nil {
+		return errBatchClosed
+	}
+	itr := newCLevelDBIterator(b.db.db.NewIterator(b.db.ro), start, end, false)
+	keys, err := deleteRangeKeys(itr, b.keys, start, end)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		b.delete(key)
+	}
 	return nil
 }
 
+// GetByteSize implements Batch.
+func (b *cLevelDBBatch) GetByteSize() (int, error) {
+	if b.batch == nil {
+		return 0, errBatchClosed
+	}
+	return b.size, nil
+}
+
+// Len implements Batch.
+func (b *cLevelDBBatch) Len() int {
+	return b.count
+}
+
 // Write implements Batch.
 func (b *cLevelDBBatch) Write() error {
 	if b.batch == nil {
@@ -77,6 +123,9 @@ func (b *cLevelDBBatch) Close() error {
 	if b.batch != nil {
 		b.batch.Close()
 		b.batch = nil
+		b.keys = nil
+		b.count = 0
+		b.size = 0
 	}
 	return nil
 }
@@ -20,34 +20,47 @@ type cLevelDBIterator struct {
 var _ Iterator = (*cLevelDBIterator)(nil)
 
 func newCLevelDBIterator(source *levigo.Iterator, start, end []byte, isReverse bool) *cLevelDBIterator {
-	if isReverse {
+	itr := &cLevelDBIterator{
+		source:    source,
+		start:     start,
+		end:       end,
+		isReverse: isReverse,
+		isInvalid: false,
+	}
+	itr.position(start, end)
+	return itr
+}
+
+// position positions the source iterator at the first item of the range [start, end) in
+// iteration order.
+func (itr *cLevelDBIterator) position(start, end []byte) {
+	if itr.isReverse {
 		if len(end) == 0 {
-			source.SeekToLast()
+			itr.source.SeekToLast()
 		} else {
-			source.Seek(end)
-			if source.Valid() {
-				eoakey := source.Key() // end or after key
+			itr.source.Seek(end)
+			if itr.source.Valid() {
+				eoakey := itr.source.Key() // end or after key
 				if bytes.Compare(end, eoakey) <= 0 {
-					source.Prev()
+					itr.source.Prev()
 				}
 			} else {
-				source.SeekToLast()
+				itr.source.SeekToLast()
 			}
 		}
 	} else {
 		if len(start) == 0 {
-			source.SeekToFirst()
+			itr.source.SeekToFirst()
 		} else {
-			source.Seek(start)
+			itr.source.Seek(start)
 		}
 	}
-	return &cLevelDBIterator{
-		source:    source,
-		start:     start,
-		end:       end,
-		isReverse: isReverse,
-		isInvalid: false,
-	}
+}
+
+// Seek implements Iterator.
+func (itr *cLevelDBIterator) Seek(key []byte) {
+	itr.isInvalid = false
+	itr.position(seekDomain(itr.start, itr.end, key, itr.isReverse))
 }
 
 // Domain implements Iterator.
@@ -0,0 +1,72 @@
+//go:build cleveldb
+// +build cleveldb
+
+package db
+
+import "github.com/jmhodges/levigo"
+
+// cLevelDBSnapshot is a cLevelDB snapshot, read through its own read options.
+type cLevelDBSnapshot struct {
+	db       *CLevelDB
+	snapshot *levigo.Snapshot
+	ro       *levigo.ReadOptions
+}
+
+var _ Snapshot = (*cLevelDBSnapshot)(nil)
+
+func newCLevelDBSnapshot(db *CLevelDB) *cLevelDBSnapshot {
+	snapshot := db.db.NewSnapshot()
+	ro := levigo.NewReadOptions()
+	ro.SetSnapshot(snapshot)
+	return &cLevelDBSnapshot{
+		db:       db,
+		snapshot: snapshot,
+		ro:       ro,
+	}
+}
+
+// Get implements Snapshot.
+func (s *cLevelDBSnapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	res, err := s.db.db.Get(s.ro, key)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Has implements Snapshot.
+func (s *cLevelDBSnapshot) Has(key []byte) (bool, error) {
+	bytes, err := s.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return bytes != nil, nil
+}
+
+// Iterator implements Snapshot.
+func (s *cLevelDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	itr := s.db.db.NewIterator(s.ro)
+	return newCLevelDBIterator(itr, start, end, false), nil
+}
+
+// ReverseIterator implements Snapshot.
+func (s *cLevelDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	itr := s.db.db.NewIterator(s.ro)
+	return newCLevelDBIterator(itr, start, end, true), nil
+}
+
+// Close implements Snapshot.
+func (s *cLevelDBSnapshot) Close() error {
+	s.ro.Close()
+	s.db.db.ReleaseSnapshot(s.snapshot)
+	return nil
+}
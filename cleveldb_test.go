@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/jmhodges/levigo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -113,3 +114,22 @@ func TestCLevelDBStats(t *testing.T) {
 
 	assert.NotEmpty(t, db.Stats())
 }
+
+func TestCLevelDBTypedStats(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, CLevelDBBackend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+	defer db.Close()
+
+	stats := db.TypedStats()
+	assert.EqualValues(t, -1, stats.MemtableSize)
+	assert.Zero(t, stats.DiskSize)
+
+	for i := int64(0); i < 100; i++ {
+		require.NoError(t, db.Set(int642Bytes(i), []byte{1}))
+	}
+	db.(*CLevelDB).DB().CompactRange(levigo.Range{}) // flushes the memtable to a table file
+	assert.Positive(t, db.TypedStats().DiskSize)
+}
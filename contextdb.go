@@ -0,0 +1,339 @@
+package db
+
+import "context"
+
+// contextDB binds the operations of a database that doesn't implement ContextDB to a context. The
+// context is checked before each operation and iterator step, so an operation that has already
+// started will run to completion.
+type contextDB struct {
+	ctx context.Context
+	db  DB
+}
+
+var (
+	_ ContextDB        = (*contextDB)(nil)
+	_ PrefixIteratorDB = (*contextDB)(nil)
+)
+
+// WithContext returns a view of the database whose operations honor the cancellation and deadline
+// of the given context, returning its error once it is done. Databases implementing ContextDB bind
+// the context themselves, others are wrapped such that the context is checked before each
+// operation, including each iterator step: once the context is done, iterators become invalid and
+// return its error from Error().
+//
+// Snapshots and transactions obtained from the view are not bound to the context.
+func WithContext(ctx context.Context, db DB) DB {
+	if cdb, ok := db.(ContextDB); ok {
+		return cdb.WithContext(ctx)
+	}
+	return &contextDB{ctx: ctx, db: db}
+}
+
+// WithContext implements ContextDB.
+func (cdb *contextDB) WithContext(ctx context.Context) DB {
+	return &contextDB{ctx: ctx, db: cdb.db}
+}
+
+// Get implements DB.
+func (cdb *contextDB) Get(key []byte) ([]byte, error) {
+	if err := cdb.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cdb.db.Get(key)
+}
+
+// Has implements DB.
+func (cdb *contextDB) Has(key []byte) (bool, error) {
+	if err := cdb.ctx.Err(); err != nil {
+		return false, err
+	}
+	return cdb.db.Has(key)
+}
+
+// MultiGet implements DB.
+func (cdb *contextDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	if err := cdb.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cdb.db.MultiGet(keys)
+}
+
+// Set implements DB.
+func (cdb *contextDB) Set(key, value []byte) error {
+	if err := cdb.ctx.Err(); err != nil {
+		return err
+	}
+	return cdb.db.Set(key, value)
+}
+
+// SetSync implements DB.
+func (cdb *contextDB) SetSync(key, value []byte) error {
+	if err := cdb.ctx.Err(); err != nil {
+		return err
+	}
+	return cdb.db.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (cdb *contextDB) Delete(key []byte) error {
+	if err := cdb.ctx.Err(); err != nil {
+		return err
+	}
+	return cdb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (cdb *contextDB) DeleteSync(key []byte) error {
+	if err := cdb.ctx.Err(); err != nil {
+		return err
+	}
+	return cdb.db.DeleteSync(key)
+}
+
+// DeleteRange implements DB.
+func (cdb *contextDB) DeleteRange(start, end []byte) error {
+	if err := cdb.ctx.Err(); err != nil {
+		return err
+	}
+	return cdb.db.DeleteRange(start, end)
+}
+
+// Iterator implements DB.
+func (cdb *contextDB) Iterator(start, end []byte) (Iterator, error) {
+	if err := cdb.ctx.Err(); err != nil {
+		return nil, err
+	}
+	itr, err := cdb.db.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newContextIterator(cdb.ctx, itr), nil
+}
+
+// ReverseIterator implements DB.
+func (cdb *contextDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	if err := cdb.ctx.Err(); err != nil {
+		return nil, err
+	}
+	itr, err := cdb.db.ReverseIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newContextIterator(cdb.ctx, itr), nil
+}
+
+// IteratorWithOptions implements DB.
+func (cdb *contextDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	if err := cdb.ctx.Err(); err != nil {
+		return nil, err
+	}
+	itr, err := cdb.db.IteratorWithOptions(start, end, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newContextIterator(cdb.ctx, itr), nil
+}
+
+// IteratePrefix implements PrefixIteratorDB.
+func (cdb *contextDB) IteratePrefix(prefix []byte) (Iterator, error) {
+	if err := cdb.ctx.Err(); err != nil {
+		return nil, err
+	}
+	itr, err := IteratePrefix(cdb.db, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return newContextIterator(cdb.ctx, itr), nil
+}
+
+// ReverseIteratePrefix implements PrefixIteratorDB.
+func (cdb *contextDB) ReverseIteratePrefix(prefix []byte) (Iterator, error) {
+	if err := cdb.ctx.Err(); err != nil {
+		return nil, err
+	}
+	itr, err := ReverseIteratePrefix(cdb.db, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return newContextIterator(cdb.ctx, itr), nil
+}
+
+// Close implements DB. It closes the underlying database regardless of the context.
+func (cdb *contextDB) Close() error {
+	return cdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (cdb *contextDB) NewBatch() Batch {
+	return &contextBatch{ctx: cdb.ctx, batch: cdb.db.NewBatch()}
+}
+
+// Print implements DB.
+func (cdb *contextDB) Print() error {
+	return cdb.db.Print()
+}
+
+// Stats implements DB.
+func (cdb *contextDB) Stats() map[string]string {
+	return cdb.db.Stats()
+}
+
+// TypedStats implements DB.
+func (cdb *contextDB) TypedStats() DBStats {
+	return cdb.db.TypedStats()
+}
+
+// Capabilities implements DB.
+func (cdb *contextDB) Capabilities() Capabilities {
+	return cdb.db.Capabilities()
+}
+
+// Snapshot implements DB.
+func (cdb *contextDB) Snapshot() (Snapshot, error) {
+	if err := cdb.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cdb.db.Snapshot()
+}
+
+// NewTxn implements DB.
+func (cdb *contextDB) NewTxn() (Txn, error) {
+	if err := cdb.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cdb.db.NewTxn()
+}
+
+// Checkpoint implements DB.
+func (cdb *contextDB) Checkpoint(dir string) error {
+	if err := cdb.ctx.Err(); err != nil {
+		return err
+	}
+	return cdb.db.Checkpoint(dir)
+}
+
+// contextIterator wraps an iterator, invalidating it once a context is done. Only Valid checks the
+// context, so that the current item remains accessible until the next call to it.
+type contextIterator struct {
+	ctx    context.Context
+	source Iterator
+	err    error
+}
+
+var _ Iterator = (*contextIterator)(nil)
+
+func newContextIterator(ctx context.Context, source Iterator) *contextIterator {
+	return &contextIterator{
+		ctx:    ctx,
+		source: source,
+	}
+}
+
+// Domain implements Iterator.
+func (itr *contextIterator) Domain() ([]byte, []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *contextIterator) Valid() bool {
+	if itr.err == nil {
+		itr.err = itr.ctx.Err()
+	}
+	return itr.err == nil && itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *contextIterator) Next() {
+	itr.source.Next()
+}
+
+// Seek implements Iterator.
+func (itr *contextIterator) Seek(key []byte) {
+	itr.source.Seek(key)
+}
+
+// Key implements Iterator.
+func (itr *contextIterator) Key() []byte {
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *contextIterator) Value() []byte {
+	return itr.source.Value()
+}
+
+// Error implements Iterator.
+func (itr *contextIterator) Error() error {
+	if itr.err != nil {
+		return itr.err
+	}
+	return itr.source.Error()
+}
+
+// Close implements Iterator.
+func (itr *contextIterator) Close() error {
+	return itr.source.Close()
+}
+
+// contextBatch wraps a batch, failing operations once a context is done.
+type contextBatch struct {
+	ctx   context.Context
+	batch Batch
+}
+
+var _ Batch = (*contextBatch)(nil)
+
+// Set implements Batch.
+func (b *contextBatch) Set(key, value []byte) error {
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.Set(key, value)
+}
+
+// Delete implements Batch.
+func (b *contextBatch) Delete(key []byte) error {
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.Delete(key)
+}
+
+// DeleteRange implements Batch.
+func (b *contextBatch) DeleteRange(start, end []byte) error {
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.DeleteRange(start, end)
+}
+
+// GetByteSize implements Batch.
+func (b *contextBatch) GetByteSize() (int, error) {
+	return b.batch.GetByteSize()
+}
+
+// Len implements Batch.
+func (b *contextBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *contextBatch) Write() error {
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.Write()
+}
+
+// WriteSync implements Batch.
+func (b *contextBatch) WriteSync() error {
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.WriteSync()
+}
+
+// Close implements Batch.
+func (b *contextBatch) Close() error {
+	return b.batch.Close()
+}
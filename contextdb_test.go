@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithContext(t *testing.T) {
+	db := NewMemDB()
+	for i := int64(0); i < 10; i++ {
+		require.NoError(t, db.Set(int642Bytes(i), []byte{1}))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cdb := WithContext(ctx, db)
+	batch := cdb.NewBatch()
+	defer batch.Close()
+	require.NoError(t, batch.Delete(int642Bytes(0)))
+
+	// cancelling the context should stop iteration between steps.
+	itr, err := cdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	var keys []int64
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, bytes2Int64(itr.Key()))
+		if len(keys) == 3 {
+			cancel()
+		}
+	}
+	require.Equal(t, []int64{0, 1, 2}, keys)
+	require.Equal(t, context.Canceled, itr.Error())
+	require.NoError(t, itr.Close())
+
+	// as should it fail any further operations.
+	_, err = cdb.Get(int642Bytes(1))
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, context.Canceled, cdb.Set(int642Bytes(1), []byte{2}))
+	_, err = cdb.ReverseIterator(nil, nil)
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, context.Canceled, batch.Write())
+
+	// a view with a new context should work, and share the underlying database.
+	ctx, cancel = context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	require.NoError(t, WithContext(ctx, cdb).Delete(int642Bytes(1)))
+	ok, err := db.Has(int642Bytes(1))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	_, err = WithContext(expired, db).Has(int642Bytes(2))
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	// prefix databases should bind the underlying database to the context.
+	pdb := WithContext(expired, NewPrefixDB(db, int642Bytes(2)))
+	require.IsType(t, &PrefixDB{}, pdb)
+	_, err = pdb.Get([]byte{1})
+	require.Equal(t, context.DeadlineExceeded, err)
+}
@@ -2,7 +2,9 @@ package db
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 )
 
 type BackendType string
@@ -37,11 +39,18 @@ const (
 	BadgerDBBackend BackendType = "badgerdb"
 )
 
-type dbCreator func(name string, dir string) (DB, error)
+// DBCreator creates a database with the given name in dir, configured by backend-specific options.
+// Creators must accept nil options, and ignore options they don't support.
+type DBCreator func(name string, dir string, opts Options) (DB, error)
 
-var backends = map[BackendType]dbCreator{}
+var (
+	backends    = map[BackendType]DBCreator{}
+	backendsMtx sync.RWMutex
+)
 
-func registerDBCreator(backend BackendType, creator dbCreator, force bool) {
+func registerDBCreator(backend BackendType, creator DBCreator, force bool) {
+	backendsMtx.Lock()
+	defer backendsMtx.Unlock()
 	_, ok := backends[backend]
 	if !force && ok {
 		return
@@ -49,21 +58,64 @@ func registerDBCreator(backend BackendType, creator dbCreator, force bool) {
 	backends[backend] = creator
 }
 
+// RegisterBackend makes a database backend available to NewDB and NewDBWithOptions, replacing any
+// backend already registered with the same type, including the built-in ones.
+func RegisterBackend(backend BackendType, creator DBCreator) {
+	registerDBCreator(backend, creator, true)
+}
+
+// Backends returns the registered backend types in sorted order, which depend on the build tags
+// the binary was built with.
+func Backends() []BackendType {
+	backendsMtx.RLock()
+	defer backendsMtx.RUnlock()
+	types := make([]BackendType, 0, len(backends))
+	for backend := range backends {
+		types = append(types, backend)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
 // NewDB creates a new database of type backend with the given name.
 func NewDB(name string, backend BackendType, dir string) (DB, error) {
+	return NewDBWithOptions(name, backend, dir, nil)
+}
+
+// NewDBWithOptions creates a new database of type backend with the given name, configured by the
+// backend-specific options opts, which may be nil. See the backend constructors for the options
+// they support.
+//
+// The read_only option is supported by all backends: the database is opened read-only where the
+// backend supports it, and must already exist, and writes fail with a *ReadOnlyError. Whether a
+// database can be opened read-only while another process has it open varies by backend.
+func NewDBWithOptions(name string, backend BackendType, dir string, opts Options) (DB, error) {
+	backendsMtx.RLock()
 	dbCreator, ok := backends[backend]
+	backendsMtx.RUnlock()
 	if !ok {
-		keys := make([]string, 0, len(backends))
-		for k := range backends {
+		types := Backends()
+		keys := make([]string, 0, len(types))
+		for _, k := range types {
 			keys = append(keys, string(k))
 		}
 		return nil, fmt.Errorf("unknown db_backend %s, expected one of %v",
 			backend, strings.Join(keys, ","))
 	}
 
-	db, err := dbCreator(name, dir)
+	if opts == nil {
+		opts = OptionsMap(nil)
+	}
+	readOnly, err := optBool(opts, "read_only", false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	db, err := dbCreator(name, dir, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
+	if readOnly {
+		db = NewReadOnlyDB(db)
+	}
 	return db, nil
 }
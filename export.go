@@ -0,0 +1,251 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// Export and Import use a portable stream format for database contents, which doesn't depend on
+// the backend. All checksums are CRC-32C (Castagnoli), and all fixed-size integers are big-endian.
+//
+//	stream  = magic version chunk* end
+//	magic   = "TMDBEXPT"
+//	version = byte (currently 1)
+//	chunk   = uvarint(count > 0) record{count} crc32(chunk up to the checksum)
+//	record  = uvarint(len(key)) key uvarint(len(value)) value
+//	end     = uvarint(0) uint64(total records) crc32(stream up to the checksum)
+//
+// Records are ordered by key. Import only writes a chunk once its checksum has been verified.
+const (
+	exportMagic   = "TMDBEXPT"
+	exportVersion = 1
+
+	// Approximate size of the chunks written by Export.
+	exportChunkSize = 1 << 20
+
+	// Size of the batches used by Import.
+	importBatchSize = 16 << 20
+)
+
+var exportCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Export writes the items in the domain [start, end) of the database to w, in a portable,
+// versioned and checksummed stream format which can be read by Import. A nil start or end exports
+// from the first or to the last key respectively, so nil, nil exports the whole database. The
+// items are read from an iterator, and thus from an implicit snapshot of the database.
+func Export(db DB, w io.Writer, start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	itr, err := db.Iterator(start, end)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+
+	bw := bufio.NewWriter(w)
+	streamCRC := crc32.New(exportCRCTable)
+	sw := io.MultiWriter(bw, streamCRC)
+	if _, err = sw.Write(append([]byte(exportMagic), exportVersion)); err != nil {
+		return err
+	}
+
+	var (
+		chunk bytes.Buffer
+		count uint64
+		total uint64
+		buf   = make([]byte, binary.MaxVarintLen64)
+	)
+	writeChunk := func() error {
+		header := buf[:binary.PutUvarint(buf, count)]
+		crc := crc32.Update(crc32.Update(0, exportCRCTable, header), exportCRCTable, chunk.Bytes())
+		if _, err := sw.Write(header); err != nil {
+			return err
+		}
+		if _, err := sw.Write(chunk.Bytes()); err != nil {
+			return err
+		}
+		if err := binary.Write(sw, binary.BigEndian, crc); err != nil {
+			return err
+		}
+		total += count
+		count = 0
+		chunk.Reset()
+		return nil
+	}
+	for ; itr.Valid(); itr.Next() {
+		for _, b := range [][]byte{itr.Key(), itr.Value()} {
+			chunk.Write(buf[:binary.PutUvarint(buf, uint64(len(b)))])
+			chunk.Write(b)
+		}
+		count++
+		if chunk.Len() >= exportChunkSize {
+			if err = writeChunk(); err != nil {
+				return err
+			}
+		}
+	}
+	if err = itr.Error(); err != nil {
+		return err
+	}
+	if count > 0 {
+		if err = writeChunk(); err != nil {
+			return err
+		}
+	}
+
+	if _, err = sw.Write(buf[:binary.PutUvarint(buf, 0)]); err != nil {
+		return err
+	}
+	if err = binary.Write(sw, binary.BigEndian, total); err != nil {
+		return err
+	}
+	if err = binary.Write(bw, binary.BigEndian, streamCRC.Sum32()); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Import reads a stream written by Export from r, and writes its items to the database in batches,
+// overwriting existing keys. Keys in the database which are not in the stream are left as is. The
+// import as a whole is not atomic: if it fails, e.g. because the stream is corrupt or truncated,
+// some of the items may already have been written.
+func Import(db DB, r io.Reader) error {
+	cr := &crcReader{
+		r:      bufio.NewReader(r),
+		stream: crc32.New(exportCRCTable),
+		chunk:  crc32.New(exportCRCTable),
+	}
+
+	header := make([]byte, len(exportMagic)+1)
+	if _, err := io.ReadFull(cr, header); err != nil {
+		return importError(err)
+	}
+	if string(header[:len(exportMagic)]) != exportMagic {
+		return errors.New("invalid export stream: bad magic")
+	}
+	if header[len(exportMagic)] != exportVersion {
+		return fmt.Errorf("unsupported export stream version %v", header[len(exportMagic)])
+	}
+
+	batch := NewAutoFlushBatch(db, importBatchSize, 0)
+	defer batch.Close()
+	var total uint64
+	for {
+		cr.chunk.Reset()
+		count, err := binary.ReadUvarint(cr)
+		if err != nil {
+			return importError(err)
+		}
+		if count == 0 {
+			break
+		}
+		var items [][2][]byte
+		for i := uint64(0); i < count; i++ {
+			key, err := readExportBytes(cr)
+			if err != nil {
+				return importError(err)
+			}
+			value, err := readExportBytes(cr)
+			if err != nil {
+				return importError(err)
+			}
+			if len(key) == 0 {
+				return errors.New("invalid export stream: empty key")
+			}
+			items = append(items, [2][]byte{key, value})
+		}
+		if err = cr.verify(cr.chunk); err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err = batch.Set(item[0], item[1]); err != nil {
+				return err
+			}
+		}
+		total += count
+	}
+
+	var expectTotal uint64
+	if err := binary.Read(cr, binary.BigEndian, &expectTotal); err != nil {
+		return importError(err)
+	}
+	if total != expectTotal {
+		return fmt.Errorf("invalid export stream: expected %v items, got %v", expectTotal, total)
+	}
+	if err := cr.verify(cr.stream); err != nil {
+		return err
+	}
+	return batch.WriteSync()
+}
+
+// importError converts EOF errors while reading an export stream to io.ErrUnexpectedEOF, since
+// a valid stream ends with a checksum.
+func importError(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("failed to read export stream: %w", err)
+}
+
+// readExportBytes reads a uvarint-prefixed byte slice from an export stream. The size hasn't been
+// verified by a checksum yet, so rather than allocating it upfront, the buffer only grows with the
+// bytes actually read.
+func readExportBytes(r *crcReader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > math.MaxInt32 {
+		return nil, fmt.Errorf("item size %v too large", size)
+	}
+	buf := bytes.NewBuffer([]byte{}) // non-nil even if empty, since values can't be nil
+	_, err = io.CopyN(buf, r, int64(size))
+	return buf.Bytes(), err
+}
+
+// crcReader reads from an export stream, computing checksums of the data read.
+type crcReader struct {
+	r      *bufio.Reader
+	stream hash.Hash32 // checksum of the whole stream
+	chunk  hash.Hash32 // checksum of the current chunk
+}
+
+// Read implements io.Reader.
+func (cr *crcReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.stream.Write(p[:n])
+	cr.chunk.Write(p[:n])
+	return n, err
+}
+
+// ReadByte implements io.ByteReader.
+func (cr *crcReader) ReadByte() (byte, error) {
+	b, err := cr.r.ReadByte()
+	if err == nil {
+		cr.stream.Write([]byte{b})
+		cr.chunk.Write([]byte{b})
+	}
+	return b, err
+}
+
+// verify reads a checksum, and compares it with the checksum of the data read so far by the given
+// hash, i.e. the stream or the current chunk.
+func (cr *crcReader) verify(h hash.Hash32) error {
+	expect := h.Sum32()
+	var crc uint32
+	if err := binary.Read(cr, binary.BigEndian, &crc); err != nil {
+		return importError(err)
+	}
+	if crc != expect {
+		return fmt.Errorf("invalid export stream: checksum mismatch, expected %08x got %08x", expect, crc)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImport(t *testing.T) {
+	source := NewMemDB()
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, source.Set(int642Bytes(int64(i)), bytes.Repeat([]byte{byte(i)}, i*10)))
+	}
+
+	// Export is chunked, so make sure the data spans several chunks.
+	buf := &bytes.Buffer{}
+	require.NoError(t, Export(source, buf, nil, nil))
+	require.Greater(t, buf.Len(), 3*exportChunkSize)
+
+	target := NewMemDB()
+	require.NoError(t, target.Set([]byte("existing"), []byte{1}))
+	require.NoError(t, Import(target, bytes.NewReader(buf.Bytes())))
+	itr, err := target.Iterator(nil, int642Bytes(1000))
+	require.NoError(t, err)
+	i := int64(0)
+	for ; itr.Valid(); itr.Next() {
+		require.Equal(t, int642Bytes(i), itr.Key())
+		require.Equal(t, bytes.Repeat([]byte{byte(i)}, int(i)*10), itr.Value())
+		i++
+	}
+	require.NoError(t, itr.Close())
+	require.EqualValues(t, 1000, i)
+	value, err := target.Get([]byte("existing"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, value)
+
+	// Ranges and empty databases can be exported too.
+	buf.Reset()
+	require.NoError(t, Export(source, buf, int642Bytes(10), int642Bytes(13)))
+	target = NewMemDB()
+	require.NoError(t, Import(target, buf))
+	itr, err = target.Iterator(nil, nil)
+	require.NoError(t, err)
+	verifyIterator(t, itr, []int64{10, 11, 12}, "range export")
+
+	buf.Reset()
+	require.NoError(t, Export(NewMemDB(), buf, nil, nil))
+	target = NewMemDB()
+	require.NoError(t, Import(target, buf))
+	assertKeyValues(t, target, map[string][]byte{})
+
+	require.Equal(t, errKeyEmpty, Export(source, buf, []byte{}, nil))
+}
+
+func TestExportImportPrefixDB(t *testing.T) {
+	source := NewMemDB()
+	require.NoError(t, source.Set([]byte("a/1"), []byte{1}))
+	require.NoError(t, source.Set([]byte("b/1"), []byte{2}))
+	require.NoError(t, source.Set([]byte("b/2"), []byte{3}))
+
+	// A namespace is exported in isolation, without its prefix.
+	buf := &bytes.Buffer{}
+	require.NoError(t, Export(NewPrefixDB(source, []byte("b/")), buf, nil, nil))
+
+	// Import it into a GoLevelDB namespace with a different prefix.
+	dir, err := ioutil.TempDir("", "export")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	target, err := NewGoLevelDB("target", dir)
+	require.NoError(t, err)
+	require.NoError(t, Import(NewPrefixDB(target, []byte("c/")), buf))
+	assertKeyValues(t, target, map[string][]byte{"c/1": {2}, "c/2": {3}})
+	require.NoError(t, target.Close())
+}
+
+func TestImportInvalid(t *testing.T) {
+	source := NewMemDB()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, source.Set(int642Bytes(int64(i)), []byte{byte(i)}))
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, Export(source, buf, nil, nil))
+	data := buf.Bytes()
+
+	testcases := map[string][]byte{
+		"empty":     {},
+		"bad magic": append([]byte("XXXXXXXX"), data[8:]...),
+		"version":   append(append([]byte(exportMagic), 2), data[9:]...),
+		"truncated": data[:len(data)-1],
+		"no end":    data[:len(data)-13],
+		"huge item": func() []byte {
+			// a key size of 2 GB, which should not be allocated before the key is read
+			huge := append([]byte(exportMagic), exportVersion, 1)
+			huge = append(huge, 0xff, 0xff, 0xff, 0xff, 0x07)
+			return append(huge, "key"...)
+		}(),
+		"corrupt": func() []byte {
+			corrupt := append([]byte{}, data...)
+			corrupt[20] ^= 0xff
+			return corrupt
+		}(),
+	}
+	for name, data := range testcases {
+		data := data
+		t.Run(name, func(t *testing.T) {
+			target := NewMemDB()
+			err := Import(target, bytes.NewReader(data))
+			require.Error(t, err)
+			if name == "truncated" || name == "empty" || name == "huge item" {
+				require.True(t, errors.Is(err, io.ErrUnexpectedEOF), err.Error())
+			}
+			// A corrupt chunk is not written.
+			if name == "corrupt" {
+				assertKeyValues(t, target, map[string][]byte{})
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"fmt"
 	"path/filepath"
+	"sync"
 
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
@@ -11,14 +12,55 @@ import (
 )
 
 func init() {
-	dbCreator := func(name string, dir string) (DB, error) {
-		return NewGoLevelDB(name, dir)
+	dbCreator := func(name string, dir string, opts Options) (DB, error) {
+		o, err := goLevelDBOptions(opts)
+		if err != nil {
+			return nil, err
+		}
+		return NewGoLevelDBWithOpts(name, dir, o)
 	}
 	registerDBCreator(GoLevelDBBackend, dbCreator, false)
 }
 
+// goLevelDBOptions converts options passed to NewDBWithOptions. Supported options are:
+//
+//   - block_cache_size: the capacity of the block cache, in bytes
+//   - write_buffer_size: the size of the memtable, in bytes
+//   - max_open_files: the capacity of the open files cache
+//   - no_sync: whether to skip syncing writes to disk, including sync writes
+//   - read_only: whether to open an existing database read-only, which fails while another
+//     process has it open for writing
+//
+// Unset options default to the goleveldb defaults.
+func goLevelDBOptions(opts Options) (*opt.Options, error) {
+	var (
+		o   opt.Options
+		err error
+	)
+	if o.BlockCacheCapacity, err = optInt(opts, "block_cache_size", 0); err != nil {
+		return nil, err
+	}
+	if o.WriteBuffer, err = optInt(opts, "write_buffer_size", 0); err != nil {
+		return nil, err
+	}
+	if o.OpenFilesCacheCapacity, err = optInt(opts, "max_open_files", 0); err != nil {
+		return nil, err
+	}
+	if o.NoSync, err = optBool(opts, "no_sync", false); err != nil {
+		return nil, err
+	}
+	if o.ReadOnly, err = optBool(opts, "read_only", false); err != nil {
+		return nil, err
+	}
+	o.ErrorIfMissing = o.ReadOnly
+	return &o, nil
+}
+
 type GoLevelDB struct {
-	db *leveldb.DB
+	db     *leveldb.DB
+	name   string
+	noSync bool
+	txnMtx sync.Mutex
 }
 
 var _ DB = (*GoLevelDB)(nil)
@@ -34,7 +76,9 @@ func NewGoLevelDBWithOpts(name string, dir string, o *opt.Options) (*GoLevelDB,
 		return nil, err
 	}
 	database := &GoLevelDB{
-		db: db,
+		db:     db,
+		name:   name,
+		noSync: o.GetNoSync(),
 	}
 	return database, nil
 }
@@ -63,6 +107,15 @@ func (db *GoLevelDB) Has(key []byte) (bool, error) {
 	return bytes != nil, nil
 }
 
+// MultiGet implements DB.
+func (db *GoLevelDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	snapshot, err := db.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return snapshotMultiGet(snapshot, keys)
+}
+
 // Set implements DB.
 func (db *GoLevelDB) Set(key []byte, value []byte) error {
 	if len(key) == 0 {
@@ -114,6 +167,29 @@ func (db *GoLevelDB) DeleteSync(key []byte) error {
 	return nil
 }
 
+// DeleteRange implements DB. LevelDB has no native range deletion, so the keys in the range are
+// deleted one by one in a single batch.
+func (db *GoLevelDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	batch := new(leveldb.Batch)
+	if err := db.deleteRange(batch, start, end); err != nil {
+		return err
+	}
+	return db.db.Write(batch, nil)
+}
+
+// deleteRange adds deletions of all keys in the range to the batch.
+func (db *GoLevelDB) deleteRange(batch *leveldb.Batch, start, end []byte) error {
+	itr := db.db.NewIterator(&util.Range{Start: start, Limit: end}, nil)
+	defer itr.Release()
+	for itr.Next() {
+		batch.Delete(itr.Key())
+	}
+	return itr.Error()
+}
+
 func (db *GoLevelDB) DB() *leveldb.DB {
 	return db.db
 }
@@ -166,6 +242,28 @@ func (db *GoLevelDB) Stats() map[string]string {
 	return stats
 }
 
+// TypedStats implements DB.
+func (db *GoLevelDB) TypedStats() DBStats {
+	stats := newDBStats()
+	var s leveldb.DBStats
+	if err := db.db.Stats(&s); err == nil {
+		stats.DiskSize = s.LevelSizes.Sum()
+		stats.OpenIterators = int64(s.AliveIterators)
+	}
+	return stats
+}
+
+// Capabilities implements DB.
+func (db *GoLevelDB) Capabilities() Capabilities {
+	return Capabilities{
+		AtomicBatch:      true,
+		SyncWrites:       !db.noSync,
+		Snapshot:         true,
+		IteratorSnapshot: true,
+		Compaction:       true,
+	}
+}
+
 func (db *GoLevelDB) ForceCompact(start, limit []byte) error {
 	return db.db.CompactRange(util.Range{Start: start, Limit: limit})
 }
@@ -175,6 +273,42 @@ func (db *GoLevelDB) NewBatch() Batch {
 	return newGoLevelDBBatch(db)
 }
 
+// Snapshot implements DB.
+func (db *GoLevelDB) Snapshot() (Snapshot, error) {
+	snapshot, err := db.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return newGoLevelDBSnapshot(snapshot), nil
+}
+
+// NewTxn implements DB.
+func (db *GoLevelDB) NewTxn() (Txn, error) {
+	snapshot, err := db.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return newSnapshotTxn(db, snapshot, &db.txnMtx), nil
+}
+
+// Checkpoint implements DB. LevelDB has no native checkpoints, so the contents of a snapshot are
+// copied into a new database, which is compacted but takes longer than copying files.
+func (db *GoLevelDB) Checkpoint(dir string) error {
+	if err := createCheckpointDir(dir); err != nil {
+		return err
+	}
+	snapshot, err := db.Snapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+	target, err := NewGoLevelDB(db.name, dir)
+	if err != nil {
+		return err
+	}
+	return copySnapshot(snapshot, target)
+}
+
 // Iterator implements DB.
 func (db *GoLevelDB) Iterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
@@ -192,3 +326,17 @@ func (db *GoLevelDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	itr := db.db.NewIterator(&util.Range{Start: start, Limit: end}, nil)
 	return newGoLevelDBIterator(itr, start, end, true), nil
 }
+
+// IteratorWithOptions implements DB.
+func (db *GoLevelDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	ro := &opt.ReadOptions{DontFillCache: opts.DontFillCache}
+	source := db.db.NewIterator(&util.Range{Start: start, Limit: end}, ro)
+	var itr Iterator = newGoLevelDBIterator(source, start, end, opts.Reverse)
+	if opts.KeysOnly {
+		itr = keysOnlyIterator{itr}
+	}
+	return itr, nil
+}
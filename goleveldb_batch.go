@@ -46,6 +46,49 @@ func (b *goLevelDBBatch) Delete(key []byte) error {
 	return nil
 }
 
+// DeleteRange implements Batch. The range is resolved to the keys present in the database and
+// the batch when it is called.
+func (b *goLevelDBBatch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if b.batch == nil {
+		return errBatchClosed
+	}
+	staged := &goLevelDBBatchRange{start: start, end: end}
+	if err := b.batch.Replay(staged); err != nil {
+		return err
+	}
+	itr, err := b.db.IteratorWithOptions(start, end, IteratorOptions{KeysOnly: true})
+	if err != nil {
+		return err
+	}
+	keys, err := deleteRangeKeys(itr, staged.keys, start, end)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		b.batch.Delete(key)
+	}
+	return nil
+}
+
+// GetByteSize implements Batch.
+func (b *goLevelDBBatch) GetByteSize() (int, error) {
+	if b.batch == nil {
+		return 0, errBatchClosed
+	}
+	return len(b.batch.Dump()), nil
+}
+
+// Len implements Batch.
+func (b *goLevelDBBatch) Len() int {
+	if b.batch == nil {
+		return 0
+	}
+	return b.batch.Len()
+}
+
 // Write implements Batch.
 func (b *goLevelDBBatch) Write() error {
 	return b.write(false)
@@ -76,3 +119,20 @@ func (b *goLevelDBBatch) Close() error {
 	}
 	return nil
 }
+
+// goLevelDBBatchRange is a leveldb.BatchReplay which collects the keys set in a batch within a
+// range.
+type goLevelDBBatchRange struct {
+	start, end []byte
+	keys       [][]byte
+}
+
+// Put implements leveldb.BatchReplay.
+func (r *goLevelDBBatchRange) Put(key, value []byte) {
+	if IsKeyInDomain(key, r.start, r.end) {
+		r.keys = append(r.keys, key)
+	}
+}
+
+// Delete implements leveldb.BatchReplay.
+func (r *goLevelDBBatchRange) Delete(key []byte) {}
@@ -17,7 +17,22 @@ type goLevelDBIterator struct {
 var _ Iterator = (*goLevelDBIterator)(nil)
 
 func newGoLevelDBIterator(source iterator.Iterator, start, end []byte, isReverse bool) *goLevelDBIterator {
-	if isReverse {
+	itr := &goLevelDBIterator{
+		source:    source,
+		start:     start,
+		end:       end,
+		isReverse: isReverse,
+		isInvalid: false,
+	}
+	itr.position(start, end)
+	return itr
+}
+
+// position positions the source iterator at the first item of the range [start, end) in
+// iteration order.
+func (itr *goLevelDBIterator) position(start, end []byte) {
+	source := itr.source
+	if itr.isReverse {
 		if end == nil {
 			source.Last()
 		} else {
@@ -38,13 +53,12 @@ func newGoLevelDBIterator(source iterator.Iterator, start, end []byte, isReverse
 			source.Seek(start)
 		}
 	}
-	return &goLevelDBIterator{
-		source:    source,
-		start:     start,
-		end:       end,
-		isReverse: isReverse,
-		isInvalid: false,
-	}
+}
+
+// Seek implements Iterator.
+func (itr *goLevelDBIterator) Seek(key []byte) {
+	itr.isInvalid = false
+	itr.position(seekDomain(itr.start, itr.end, key, itr.isReverse))
 }
 
 // Domain implements Iterator.
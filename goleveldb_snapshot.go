@@ -0,0 +1,67 @@
+package db
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// goLevelDBSnapshot is a goleveldb snapshot.
+type goLevelDBSnapshot struct {
+	snapshot *leveldb.Snapshot
+}
+
+var _ Snapshot = (*goLevelDBSnapshot)(nil)
+
+func newGoLevelDBSnapshot(snapshot *leveldb.Snapshot) *goLevelDBSnapshot {
+	return &goLevelDBSnapshot{
+		snapshot: snapshot,
+	}
+}
+
+// Get implements Snapshot.
+func (s *goLevelDBSnapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	res, err := s.snapshot.Get(key, nil)
+	if err != nil {
+		if err == errors.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// Has implements Snapshot.
+func (s *goLevelDBSnapshot) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	return s.snapshot.Has(key, nil)
+}
+
+// Iterator implements Snapshot.
+func (s *goLevelDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	itr := s.snapshot.NewIterator(&util.Range{Start: start, Limit: end}, nil)
+	return newGoLevelDBIterator(itr, start, end, false), nil
+}
+
+// ReverseIterator implements Snapshot.
+func (s *goLevelDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	itr := s.snapshot.NewIterator(&util.Range{Start: start, Limit: end}, nil)
+	return newGoLevelDBIterator(itr, start, end, true), nil
+}
+
+// Close implements Snapshot.
+func (s *goLevelDBSnapshot) Close() error {
+	s.snapshot.Release()
+	return nil
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"fmt"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -28,6 +29,17 @@ func TestGoLevelDBNewGoLevelDB(t *testing.T) {
 	defer ro2.Close()
 }
 
+func TestGoLevelDBCapabilitiesNoSync(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	defer cleanupDBDir(dir, name)
+
+	db, err := NewDBWithOptions(name, GoLevelDBBackend, dir, OptionsMap{"no_sync": true})
+	require.NoError(t, err)
+	defer db.Close()
+	require.False(t, db.Capabilities().SyncWrites)
+}
+
 func BenchmarkGoLevelDBRandomReadsWrites(b *testing.B) {
 	name := fmt.Sprintf("test_%x", randStr(12))
 	db, err := NewGoLevelDB(name, "")
@@ -0,0 +1,406 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// OpKind identifies a database operation.
+type OpKind string
+
+// Operations reported to Hooks, and used as metric labels by MetricsDB.
+const (
+	OpGet            OpKind = "get"
+	OpHas            OpKind = "has"
+	OpMultiGet       OpKind = "multi_get"
+	OpSet            OpKind = "set"
+	OpSetSync        OpKind = "set_sync"
+	OpDelete         OpKind = "delete"
+	OpDeleteSync     OpKind = "delete_sync"
+	OpDeleteRange    OpKind = "delete_range"
+	OpIterator       OpKind = "iterator"
+	OpIteratorNext   OpKind = "iterator_next"
+	OpIteratorSeek   OpKind = "iterator_seek"
+	OpBatchWrite     OpKind = "batch_write"
+	OpBatchWriteSync OpKind = "batch_write_sync"
+)
+
+// OpInfo describes a database operation.
+type OpInfo struct {
+	// Kind is the kind of operation.
+	Kind OpKind
+
+	// KeySize is the size of the key given to the operation, or the total size of the keys or
+	// range bounds for operations taking several.
+	KeySize int
+
+	// Size is the total size of the keys and values written by write operations, including batch
+	// writes. It is 0 for other operations.
+	Size int
+}
+
+// OpResult describes the outcome of a database operation.
+type OpResult struct {
+	// Duration is the time the operation took.
+	Duration time.Duration
+
+	// Err is the error returned by the operation, if any.
+	Err error
+}
+
+// Hooks are called around the operations of databases, batches and iterators wrapped with
+// NewHookedDB, NewHookedBatch or NewHookedIterator, e.g. to record tracing spans.
+//
+// Before is called before an operation starts, with the context the wrapper is bound to via
+// WithContext, or context.Background(). The context it returns is passed to After, which is called
+// once the operation has completed, such that hooks can carry state such as a span between the
+// calls. Hooks must be safe for concurrent use, and should be fast, since they are called for
+// every operation, including each iterator step.
+type Hooks interface {
+	Before(ctx context.Context, op OpInfo) context.Context
+	After(ctx context.Context, op OpInfo, result OpResult)
+}
+
+// hook calls the hooks around fn.
+func hook(ctx context.Context, hooks Hooks, op OpInfo, fn func() error) error {
+	ctx = hooks.Before(ctx, op)
+	start := time.Now()
+	err := fn()
+	hooks.After(ctx, op, OpResult{Duration: time.Since(start), Err: err})
+	return err
+}
+
+// hookedDB calls hooks around the operations of a database.
+type hookedDB struct {
+	ctx   context.Context
+	db    DB
+	hooks Hooks
+}
+
+var (
+	_ ContextDB        = (*hookedDB)(nil)
+	_ PrefixIteratorDB = (*hookedDB)(nil)
+)
+
+// NewHookedDB wraps a database, calling hooks around its operations and the operations of its
+// batches and iterators. The returned database implements ContextDB, passing the bound context to
+// the hooks as well as to the underlying database. Snapshots and transactions are not hooked.
+func NewHookedDB(db DB, hooks Hooks) DB {
+	return &hookedDB{ctx: context.Background(), db: db, hooks: hooks}
+}
+
+// WithContext implements ContextDB.
+func (hdb *hookedDB) WithContext(ctx context.Context) DB {
+	return &hookedDB{ctx: ctx, db: WithContext(ctx, hdb.db), hooks: hdb.hooks}
+}
+
+// Get implements DB.
+func (hdb *hookedDB) Get(key []byte) (value []byte, err error) {
+	err = hook(hdb.ctx, hdb.hooks, OpInfo{Kind: OpGet, KeySize: len(key)}, func() error {
+		value, err = hdb.db.Get(key)
+		return err
+	})
+	return value, err
+}
+
+// Has implements DB.
+func (hdb *hookedDB) Has(key []byte) (ok bool, err error) {
+	err = hook(hdb.ctx, hdb.hooks, OpInfo{Kind: OpHas, KeySize: len(key)}, func() error {
+		ok, err = hdb.db.Has(key)
+		return err
+	})
+	return ok, err
+}
+
+// MultiGet implements DB.
+func (hdb *hookedDB) MultiGet(keys [][]byte) (values [][]byte, err error) {
+	keySize := 0
+	for _, key := range keys {
+		keySize += len(key)
+	}
+	err = hook(hdb.ctx, hdb.hooks, OpInfo{Kind: OpMultiGet, KeySize: keySize}, func() error {
+		values, err = hdb.db.MultiGet(keys)
+		return err
+	})
+	return values, err
+}
+
+// Set implements DB.
+func (hdb *hookedDB) Set(key, value []byte) error {
+	op := OpInfo{Kind: OpSet, KeySize: len(key), Size: len(key) + len(value)}
+	return hook(hdb.ctx, hdb.hooks, op, func() error {
+		return hdb.db.Set(key, value)
+	})
+}
+
+// SetSync implements DB.
+func (hdb *hookedDB) SetSync(key, value []byte) error {
+	op := OpInfo{Kind: OpSetSync, KeySize: len(key), Size: len(key) + len(value)}
+	return hook(hdb.ctx, hdb.hooks, op, func() error {
+		return hdb.db.SetSync(key, value)
+	})
+}
+
+// Delete implements DB.
+func (hdb *hookedDB) Delete(key []byte) error {
+	op := OpInfo{Kind: OpDelete, KeySize: len(key), Size: len(key)}
+	return hook(hdb.ctx, hdb.hooks, op, func() error {
+		return hdb.db.Delete(key)
+	})
+}
+
+// DeleteSync implements DB.
+func (hdb *hookedDB) DeleteSync(key []byte) error {
+	op := OpInfo{Kind: OpDeleteSync, KeySize: len(key), Size: len(key)}
+	return hook(hdb.ctx, hdb.hooks, op, func() error {
+		return hdb.db.DeleteSync(key)
+	})
+}
+
+// DeleteRange implements DB.
+func (hdb *hookedDB) DeleteRange(start, end []byte) error {
+	op := OpInfo{Kind: OpDeleteRange, KeySize: len(start) + len(end), Size: len(start) + len(end)}
+	return hook(hdb.ctx, hdb.hooks, op, func() error {
+		return hdb.db.DeleteRange(start, end)
+	})
+}
+
+// Iterator implements DB.
+func (hdb *hookedDB) Iterator(start, end []byte) (Iterator, error) {
+	return hdb.newIterator(len(start)+len(end), func() (Iterator, error) {
+		return hdb.db.Iterator(start, end)
+	})
+}
+
+// ReverseIterator implements DB.
+func (hdb *hookedDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return hdb.newIterator(len(start)+len(end), func() (Iterator, error) {
+		return hdb.db.ReverseIterator(start, end)
+	})
+}
+
+// IteratorWithOptions implements DB.
+func (hdb *hookedDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	return hdb.newIterator(len(start)+len(end), func() (Iterator, error) {
+		return hdb.db.IteratorWithOptions(start, end, opts)
+	})
+}
+
+// IteratePrefix implements PrefixIteratorDB.
+func (hdb *hookedDB) IteratePrefix(prefix []byte) (Iterator, error) {
+	return hdb.newIterator(len(prefix), func() (Iterator, error) {
+		return IteratePrefix(hdb.db, prefix)
+	})
+}
+
+// ReverseIteratePrefix implements PrefixIteratorDB.
+func (hdb *hookedDB) ReverseIteratePrefix(prefix []byte) (Iterator, error) {
+	return hdb.newIterator(len(prefix), func() (Iterator, error) {
+		return ReverseIteratePrefix(hdb.db, prefix)
+	})
+}
+
+// newIterator creates and wraps an iterator.
+func (hdb *hookedDB) newIterator(keySize int, fn func() (Iterator, error)) (itr Iterator, err error) {
+	err = hook(hdb.ctx, hdb.hooks, OpInfo{Kind: OpIterator, KeySize: keySize}, func() error {
+		itr, err = fn()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hookedIterator{ctx: hdb.ctx, source: itr, hooks: hdb.hooks}, nil
+}
+
+// Close implements DB.
+func (hdb *hookedDB) Close() error {
+	return hdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (hdb *hookedDB) NewBatch() Batch {
+	return &hookedBatch{ctx: hdb.ctx, source: hdb.db.NewBatch(), hooks: hdb.hooks}
+}
+
+// Print implements DB.
+func (hdb *hookedDB) Print() error {
+	return hdb.db.Print()
+}
+
+// Stats implements DB.
+func (hdb *hookedDB) Stats() map[string]string {
+	return hdb.db.Stats()
+}
+
+// TypedStats implements DB.
+func (hdb *hookedDB) TypedStats() DBStats {
+	return hdb.db.TypedStats()
+}
+
+// Capabilities implements DB.
+func (hdb *hookedDB) Capabilities() Capabilities {
+	return hdb.db.Capabilities()
+}
+
+// Snapshot implements DB.
+func (hdb *hookedDB) Snapshot() (Snapshot, error) {
+	return hdb.db.Snapshot()
+}
+
+// NewTxn implements DB.
+func (hdb *hookedDB) NewTxn() (Txn, error) {
+	return hdb.db.NewTxn()
+}
+
+// Checkpoint implements DB.
+func (hdb *hookedDB) Checkpoint(dir string) error {
+	return hdb.db.Checkpoint(dir)
+}
+
+// hookedBatch calls hooks around the writes of a batch.
+type hookedBatch struct {
+	ctx    context.Context
+	source Batch
+	hooks  Hooks
+}
+
+var _ Batch = (*hookedBatch)(nil)
+
+// NewHookedBatch wraps a batch, calling hooks around its writes with a background context.
+func NewHookedBatch(batch Batch, hooks Hooks) Batch {
+	return &hookedBatch{ctx: context.Background(), source: batch, hooks: hooks}
+}
+
+// Set implements Batch.
+func (b *hookedBatch) Set(key, value []byte) error {
+	return b.source.Set(key, value)
+}
+
+// Delete implements Batch.
+func (b *hookedBatch) Delete(key []byte) error {
+	return b.source.Delete(key)
+}
+
+// DeleteRange implements Batch.
+func (b *hookedBatch) DeleteRange(start, end []byte) error {
+	return b.source.DeleteRange(start, end)
+}
+
+// GetByteSize implements Batch.
+func (b *hookedBatch) GetByteSize() (int, error) {
+	return b.source.GetByteSize()
+}
+
+// Len implements Batch.
+func (b *hookedBatch) Len() int {
+	return b.source.Len()
+}
+
+// Write implements Batch.
+func (b *hookedBatch) Write() error {
+	size, _ := b.source.GetByteSize()
+	return hook(b.ctx, b.hooks, OpInfo{Kind: OpBatchWrite, Size: size}, b.source.Write)
+}
+
+// WriteSync implements Batch.
+func (b *hookedBatch) WriteSync() error {
+	size, _ := b.source.GetByteSize()
+	return hook(b.ctx, b.hooks, OpInfo{Kind: OpBatchWriteSync, Size: size}, b.source.WriteSync)
+}
+
+// Close implements Batch.
+func (b *hookedBatch) Close() error {
+	return b.source.Close()
+}
+
+// hookedIterator calls hooks around the steps of an iterator.
+type hookedIterator struct {
+	ctx    context.Context
+	source Iterator
+	hooks  Hooks
+}
+
+var _ Iterator = (*hookedIterator)(nil)
+
+// NewHookedIterator wraps an iterator, calling hooks around its steps with a background context.
+func NewHookedIterator(itr Iterator, hooks Hooks) Iterator {
+	return &hookedIterator{ctx: context.Background(), source: itr, hooks: hooks}
+}
+
+// Domain implements Iterator.
+func (itr *hookedIterator) Domain() (start []byte, end []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *hookedIterator) Valid() bool {
+	return itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *hookedIterator) Next() {
+	_ = hook(itr.ctx, itr.hooks, OpInfo{Kind: OpIteratorNext}, func() error {
+		itr.source.Next()
+		return nil
+	})
+}
+
+// Seek implements Iterator.
+func (itr *hookedIterator) Seek(key []byte) {
+	_ = hook(itr.ctx, itr.hooks, OpInfo{Kind: OpIteratorSeek, KeySize: len(key)}, func() error {
+		itr.source.Seek(key)
+		return itr.source.Error()
+	})
+}
+
+// Key implements Iterator.
+func (itr *hookedIterator) Key() []byte {
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *hookedIterator) Value() []byte {
+	return itr.source.Value()
+}
+
+// Error implements Iterator.
+func (itr *hookedIterator) Error() error {
+	return itr.source.Error()
+}
+
+// Close implements Iterator.
+func (itr *hookedIterator) Close() error {
+	return itr.source.Close()
+}
+
+// SlowOpLogger implements Hooks by logging operations that take at least a threshold duration.
+type SlowOpLogger struct {
+	threshold time.Duration
+	logger    *log.Logger
+}
+
+var _ Hooks = (*SlowOpLogger)(nil)
+
+// NewSlowOpLogger creates a SlowOpLogger which logs operations taking at least threshold to
+// logger, or to the standard logger if nil.
+func NewSlowOpLogger(threshold time.Duration, logger *log.Logger) *SlowOpLogger {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &SlowOpLogger{threshold: threshold, logger: logger}
+}
+
+// Before implements Hooks.
+func (l *SlowOpLogger) Before(ctx context.Context, op OpInfo) context.Context {
+	return ctx
+}
+
+// After implements Hooks.
+func (l *SlowOpLogger) After(ctx context.Context, op OpInfo, result OpResult) {
+	if result.Duration < l.threshold {
+		return
+	}
+	l.logger.Printf("slow database operation: op=%s key_size=%d size=%d duration=%s err=%v",
+		op.Kind, op.KeySize, op.Size, result.Duration, result.Err)
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type hooksCtxKey struct{}
+
+// recordingHooks records the operations passed to Hooks.
+type recordingHooks struct {
+	mtx     sync.Mutex
+	ops     []OpInfo
+	results []OpResult
+	ctxs    []interface{}
+}
+
+func (h *recordingHooks) Before(ctx context.Context, op OpInfo) context.Context {
+	return context.WithValue(ctx, hooksCtxKey{}, op.Kind)
+}
+
+func (h *recordingHooks) After(ctx context.Context, op OpInfo, result OpResult) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.ops = append(h.ops, op)
+	h.results = append(h.results, result)
+	h.ctxs = append(h.ctxs, ctx.Value(hooksCtxKey{}))
+}
+
+func TestHookedDB(t *testing.T) {
+	hooks := &recordingHooks{}
+	db := NewHookedDB(NewMemDB(), hooks)
+
+	require.NoError(t, db.Set([]byte("a"), []byte{1, 2, 3}))
+	value, err := db.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, value)
+	_, err = db.Get(nil)
+	require.Error(t, err)
+
+	batch := db.NewBatch()
+	require.NoError(t, batch.Set([]byte("b"), []byte{1}))
+	require.NoError(t, batch.Delete([]byte("a")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	itr, err := db.Iterator([]byte("a"), nil)
+	require.NoError(t, err)
+	for ; itr.Valid(); itr.Next() {
+	}
+	itr.Seek([]byte("b"))
+	require.True(t, itr.Valid())
+	require.NoError(t, itr.Close())
+
+	require.Equal(t, []OpInfo{
+		{Kind: OpSet, KeySize: 1, Size: 4},
+		{Kind: OpGet, KeySize: 1},
+		{Kind: OpGet},
+		{Kind: OpBatchWrite, Size: 3},
+		{Kind: OpIterator, KeySize: 1},
+		{Kind: OpIteratorNext},
+		{Kind: OpIteratorSeek, KeySize: 1},
+	}, hooks.ops)
+	for i, result := range hooks.results {
+		require.Equal(t, hooks.ops[i].Kind, hooks.ctxs[i], "context from Before not passed to After")
+		if i == 2 {
+			require.Equal(t, errKeyEmpty, result.Err)
+		} else {
+			require.NoError(t, result.Err)
+		}
+	}
+
+	// Contexts bound with WithContext are passed to the hooks and the database.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = WithContext(ctx, db).Get([]byte("b"))
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, OpGet, hooks.ops[len(hooks.ops)-1].Kind)
+	require.Equal(t, context.Canceled, hooks.results[len(hooks.results)-1].Err)
+
+	require.NoError(t, db.Close())
+}
+
+func TestSlowOpLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewSlowOpLogger(time.Second, log.New(buf, "", 0))
+
+	logger.After(context.Background(), OpInfo{Kind: OpGet, KeySize: 3}, OpResult{Duration: time.Millisecond})
+	require.Empty(t, buf.String())
+
+	logger.After(context.Background(), OpInfo{Kind: OpSet, KeySize: 3, Size: 10},
+		OpResult{Duration: 2 * time.Second, Err: errKeyEmpty})
+	require.Equal(t, "slow database operation: op=set key_size=3 size=10 duration=2s err=key cannot be empty\n",
+		buf.String())
+
+	// Any database can be wrapped with the logger.
+	db := NewHookedDB(NewMemDB(), NewSlowOpLogger(0, log.New(buf, "", 0)))
+	buf.Reset()
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.Contains(t, buf.String(), "op=set key_size=1 size=2")
+}
@@ -0,0 +1,186 @@
+package db
+
+// indexedBatch is an IndexedBatch which wraps a batch from the underlying database, indexing its
+// pending writes in a MemDB (with nil values for deletions) so they can be read back.
+type indexedBatch struct {
+	db     DB
+	batch  Batch
+	writes *MemDB
+}
+
+var _ IndexedBatch = (*indexedBatch)(nil)
+
+// NewIndexedBatch creates a new batch on the given database, which can read its own pending writes
+// layered on top of the database's contents. It is similar to RocksDB's WriteBatchWithIndex, but is
+// implemented generically and is available for all backends.
+func NewIndexedBatch(db DB) IndexedBatch {
+	return &indexedBatch{
+		db:     db,
+		batch:  db.NewBatch(),
+		writes: NewMemDB(),
+	}
+}
+
+// Set implements Batch.
+func (b *indexedBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.writes == nil {
+		return errBatchClosed
+	}
+	if err := b.batch.Set(key, value); err != nil {
+		return err
+	}
+	b.writes.set(key, value)
+	return nil
+}
+
+// Delete implements Batch.
+func (b *indexedBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.writes == nil {
+		return errBatchClosed
+	}
+	if err := b.batch.Delete(key); err != nil {
+		return err
+	}
+	b.writes.set(key, nil)
+	return nil
+}
+
+// DeleteRange implements Batch.
+func (b *indexedBatch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if b.writes == nil {
+		return errBatchClosed
+	}
+	// Index the deletion as tombstones for the keys currently visible in the range.
+	itr, err := b.Iterator(start, end)
+	if err != nil {
+		return err
+	}
+	var keys [][]byte
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, itr.Key())
+	}
+	if err = itr.Error(); err != nil {
+		itr.Close()
+		return err
+	}
+	if err = itr.Close(); err != nil {
+		return err
+	}
+	if err = b.batch.DeleteRange(start, end); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		b.writes.set(key, nil)
+	}
+	return nil
+}
+
+// GetByteSize implements Batch.
+func (b *indexedBatch) GetByteSize() (int, error) {
+	if b.writes == nil {
+		return 0, errBatchClosed
+	}
+	return b.batch.GetByteSize()
+}
+
+// Len implements Batch.
+func (b *indexedBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *indexedBatch) Write() error {
+	if b.writes == nil {
+		return errBatchClosed
+	}
+	if err := b.batch.Write(); err != nil {
+		return err
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// WriteSync implements Batch.
+func (b *indexedBatch) WriteSync() error {
+	if b.writes == nil {
+		return errBatchClosed
+	}
+	if err := b.batch.WriteSync(); err != nil {
+		return err
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// Close implements Batch.
+func (b *indexedBatch) Close() error {
+	b.writes = nil
+	return b.batch.Close()
+}
+
+// Get implements IndexedBatch.
+func (b *indexedBatch) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	if b.writes == nil {
+		return nil, errBatchClosed
+	}
+	if i := b.writes.btree.Get(newKey(key)); i != nil {
+		return i.(item).value, nil
+	}
+	return b.db.Get(key)
+}
+
+// Has implements IndexedBatch.
+func (b *indexedBatch) Has(key []byte) (bool, error) {
+	value, err := b.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return value != nil, nil
+}
+
+// Iterator implements IndexedBatch.
+func (b *indexedBatch) Iterator(start, end []byte) (Iterator, error) {
+	return b.newIterator(start, end, false)
+}
+
+// ReverseIterator implements IndexedBatch.
+func (b *indexedBatch) ReverseIterator(start, end []byte) (Iterator, error) {
+	return b.newIterator(start, end, true)
+}
+
+func (b *indexedBatch) newIterator(start, end []byte, isReverse bool) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	if b.writes == nil {
+		return nil, errBatchClosed
+	}
+	var (
+		parent Iterator
+		err    error
+	)
+	if isReverse {
+		parent, err = b.db.ReverseIterator(start, end)
+	} else {
+		parent, err = b.db.Iterator(start, end)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newMergeIterator(parent, newMemDBIterator(b.writes, start, end, isReverse), isReverse), nil
+}
@@ -0,0 +1,38 @@
+package db
+
+// keysOnlyIterator wraps an iterator to hide its values, for backends which can't avoid reading
+// values during iteration.
+type keysOnlyIterator struct {
+	Iterator
+}
+
+var _ Iterator = keysOnlyIterator{}
+
+// Value implements Iterator.
+func (itr keysOnlyIterator) Value() []byte {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+	return nil
+}
+
+// iteratorWithOptions implements DB.IteratorWithOptions for backends without native support for
+// iterator options, by creating a regular iterator and hiding the values of key-only iterators.
+func iteratorWithOptions(db DB, start, end []byte, opts IteratorOptions) (Iterator, error) {
+	var (
+		itr Iterator
+		err error
+	)
+	if opts.Reverse {
+		itr, err = db.ReverseIterator(start, end)
+	} else {
+		itr, err = db.Iterator(start, end)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.KeysOnly {
+		itr = keysOnlyIterator{itr}
+	}
+	return itr, nil
+}
@@ -1,8 +1,15 @@
 package db
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/google/btree"
@@ -11,10 +18,13 @@ import (
 const (
 	// The approximate number of items and children per B-tree node. Tuned with benchmarks.
 	bTreeDegree = 32
+
+	// Name of the file MemDB checkpoints are written to, within the checkpoint directory.
+	memDBCheckpointFile = "memdb.checkpoint"
 )
 
 func init() {
-	registerDBCreator(MemDBBackend, func(name, dir string) (DB, error) {
+	registerDBCreator(MemDBBackend, func(name, dir string, opts Options) (DB, error) {
 		return NewMemDB(), nil
 	}, false)
 }
@@ -49,8 +59,9 @@ func newPair(key, value []byte) item {
 // already specify that keys and values should be considered read-only, but this is especially
 // important with MemDB.
 type MemDB struct {
-	mtx   sync.RWMutex
-	btree *btree.BTree
+	mtx    sync.RWMutex
+	btree  *btree.BTree
+	txnMtx sync.Mutex
 }
 
 var _ DB = (*MemDB)(nil)
@@ -89,6 +100,25 @@ func (db *MemDB) Has(key []byte) (bool, error) {
 	return db.btree.Has(newKey(key)), nil
 }
 
+// MultiGet implements DB.
+func (db *MemDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+	}
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+
+	values := make([][]byte, len(keys))
+	for n, key := range keys {
+		if i := db.btree.Get(newKey(key)); i != nil {
+			values[n] = i.(item).value
+		}
+	}
+	return values, nil
+}
+
 // Set implements DB.
 func (db *MemDB) Set(key []byte, value []byte) error {
 	if len(key) == 0 {
@@ -136,6 +166,40 @@ func (db *MemDB) DeleteSync(key []byte) error {
 	return db.Delete(key)
 }
 
+// DeleteRange implements DB.
+func (db *MemDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+
+	db.deleteRange(start, end)
+	return nil
+}
+
+// deleteRange deletes a range of keys without locking the mutex.
+func (db *MemDB) deleteRange(start, end []byte) {
+	var items []btree.Item
+	visitor := func(i btree.Item) bool {
+		items = append(items, i)
+		return true
+	}
+	switch {
+	case start == nil && end == nil:
+		db.btree.Ascend(visitor)
+	case start == nil:
+		db.btree.AscendLessThan(newKey(end), visitor)
+	case end == nil:
+		db.btree.AscendGreaterOrEqual(newKey(start), visitor)
+	default:
+		db.btree.AscendRange(newKey(start), newKey(end), visitor)
+	}
+	for _, i := range items {
+		db.btree.Delete(i)
+	}
+}
+
 // Close implements DB.
 func (db *MemDB) Close() error {
 	// Close is a noop since for an in-memory database, we don't have a destination to flush
@@ -168,13 +232,138 @@ func (db *MemDB) Stats() map[string]string {
 	return stats
 }
 
+// TypedStats implements DB.
+func (db *MemDB) TypedStats() DBStats {
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+
+	stats := newDBStats()
+	stats.ApproximateKeys = int64(db.btree.Len())
+	stats.DiskSize = 0
+	return stats
+}
+
+// Capabilities implements DB. MemDB is not persistent, so sync writes are not supported.
+func (db *MemDB) Capabilities() Capabilities {
+	return Capabilities{
+		AtomicBatch:      true,
+		Snapshot:         true,
+		IteratorSnapshot: true,
+	}
+}
+
 // NewBatch implements DB.
 func (db *MemDB) NewBatch() Batch {
 	return newMemDBBatch(db)
 }
 
+// Snapshot implements DB.
+func (db *MemDB) Snapshot() (Snapshot, error) {
+	// Cloning updates the copy-on-write state of the original tree, so we need a write lock.
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+
+	return newMemDBSnapshot(db.btree.Clone()), nil
+}
+
+// NewTxn implements DB.
+func (db *MemDB) NewTxn() (Txn, error) {
+	snapshot, err := db.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return newSnapshotTxn(db, snapshot, &db.txnMtx), nil
+}
+
+// Checkpoint implements DB. Since MemDB isn't persistent, the checkpoint can't be opened with NewDB,
+// but it can be loaded with NewMemDBFromCheckpoint. Its items are written as uvarint-prefixed keys
+// and values, in key order.
+func (db *MemDB) Checkpoint(dir string) error {
+	if err := createCheckpointDir(dir); err != nil {
+		return err
+	}
+	db.mtx.Lock()
+	tree := db.btree.Clone()
+	db.mtx.Unlock()
+
+	f, err := os.OpenFile(filepath.Join(dir, memDBCheckpointFile), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	buf := make([]byte, binary.MaxVarintLen64)
+	tree.Ascend(func(i btree.Item) bool {
+		item := i.(item)
+		for _, b := range [][]byte{item.key, item.value} {
+			if _, err = w.Write(buf[:binary.PutUvarint(buf, uint64(len(b)))]); err != nil {
+				return false
+			}
+			if _, err = w.Write(b); err != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// NewMemDBFromCheckpoint creates a new in-memory database with the contents of a checkpoint written
+// by MemDB.Checkpoint to the directory dir.
+func NewMemDBFromCheckpoint(dir string) (*MemDB, error) {
+	f, err := os.Open(filepath.Join(dir, memDBCheckpointFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	db := NewMemDB()
+	r := bufio.NewReader(f)
+	for {
+		key, err := readMemDBCheckpointBytes(r)
+		if err == io.EOF {
+			return db, nil
+		} else if err != nil {
+			return nil, err
+		}
+		value, err := readMemDBCheckpointBytes(r)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		db.set(key, value)
+	}
+}
+
+// readMemDBCheckpointBytes reads a uvarint-prefixed byte slice from a MemDB checkpoint. It returns
+// io.EOF if there is no more data, and io.ErrUnexpectedEOF if the data is truncated.
+func readMemDBCheckpointBytes(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > math.MaxInt32 {
+		return nil, errors.New("invalid MemDB checkpoint item size")
+	}
+	b := make([]byte, size)
+	if _, err = io.ReadFull(r, b); err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return b, err
+}
+
 // Iterator implements DB.
-// Takes out a read-lock on the database until the iterator is closed.
+// Iterates over a copy-on-write clone of the database, without holding any locks.
 func (db *MemDB) Iterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
@@ -183,7 +372,7 @@ func (db *MemDB) Iterator(start, end []byte) (Iterator, error) {
 }
 
 // ReverseIterator implements DB.
-// Takes out a read-lock on the database until the iterator is closed.
+// Iterates over a copy-on-write clone of the database, without holding any locks.
 func (db *MemDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
@@ -191,7 +380,13 @@ func (db *MemDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	return newMemDBIterator(db, start, end, true), nil
 }
 
-// IteratorNoMtx makes an iterator with no mutex.
+// IteratorWithOptions implements DB.
+func (db *MemDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	return iteratorWithOptions(db, start, end, opts)
+}
+
+// IteratorNoMtx makes an iterator with no mutex, over the live database rather than a snapshot.
+// No writes may happen within its domain while the iterator exists.
 func (db *MemDB) IteratorNoMtx(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
@@ -199,7 +394,8 @@ func (db *MemDB) IteratorNoMtx(start, end []byte) (Iterator, error) {
 	return newMemDBIteratorMtxChoice(db, start, end, false, false), nil
 }
 
-// ReverseIteratorNoMtx makes an iterator with no mutex.
+// ReverseIteratorNoMtx makes an iterator with no mutex, over the live database rather than a
+// snapshot. No writes may happen within its domain while the iterator exists.
 func (db *MemDB) ReverseIteratorNoMtx(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
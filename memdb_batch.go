@@ -16,10 +16,60 @@ type operation struct {
 	value []byte
 }
 
+// deleteRangeKeys resolves a range deletion in a batch of a backend without native range deletion
+// to the keys in the domain [start, end) which exist in the database, as read from itr, or are
+// staged, i.e. set earlier in the batch. Each key is returned once, such that the deletion counts
+// as one operation per key in Len. The iterator must cover the domain, and is closed.
+func deleteRangeKeys(itr Iterator, staged [][]byte, start, end []byte) ([][]byte, error) {
+	defer itr.Close()
+
+	keys := [][]byte{}
+	seen := make(map[string]bool)
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, cp(itr.Key()))
+		seen[string(itr.Key())] = true
+	}
+	if err := itr.Error(); err != nil {
+		return nil, err
+	}
+	for _, key := range staged {
+		if !seen[string(key)] && IsKeyInDomain(key, start, end) {
+			keys = append(keys, key)
+			seen[string(key)] = true
+		}
+	}
+	return keys, nil
+}
+
+// deleteRangeOps is like deleteRangeKeys, for batches which stage their operations, returning
+// delete operations.
+func deleteRangeOps(db DB, ops []operation, start, end []byte) ([]operation, error) {
+	itr, err := db.IteratorWithOptions(start, end, IteratorOptions{KeysOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	var staged [][]byte
+	for _, op := range ops {
+		if op.opType == opTypeSet {
+			staged = append(staged, op.key)
+		}
+	}
+	keys, err := deleteRangeKeys(itr, staged, start, end)
+	if err != nil {
+		return nil, err
+	}
+	deletes := make([]operation, 0, len(keys))
+	for _, key := range keys {
+		deletes = append(deletes, operation{opTypeDelete, key, nil})
+	}
+	return deletes, nil
+}
+
 // memDBBatch handles in-memory batching.
 type memDBBatch struct {
-	db  *MemDB
-	ops []operation
+	db   *MemDB
+	ops  []operation
+	size int // size of the keys and values in ops
 }
 
 var _ Batch = (*memDBBatch)(nil)
@@ -44,6 +94,7 @@ func (b *memDBBatch) Set(key, value []byte) error {
 		return errBatchClosed
 	}
 	b.ops = append(b.ops, operation{opTypeSet, key, value})
+	b.size += len(key) + len(value)
 	return nil
 }
 
@@ -56,9 +107,42 @@ func (b *memDBBatch) Delete(key []byte) error {
 		return errBatchClosed
 	}
 	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
+	b.size += len(key)
+	return nil
+}
+
+// DeleteRange implements Batch. The range is resolved to deletions of the keys in it when called.
+func (b *memDBBatch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	deletes, err := deleteRangeOps(b.db, b.ops, start, end)
+	if err != nil {
+		return err
+	}
+	for _, op := range deletes {
+		b.ops = append(b.ops, op)
+		b.size += len(op.key)
+	}
 	return nil
 }
 
+// GetByteSize implements Batch.
+func (b *memDBBatch) GetByteSize() (int, error) {
+	if b.ops == nil {
+		return 0, errBatchClosed
+	}
+	return b.size, nil
+}
+
+// Len implements Batch.
+func (b *memDBBatch) Len() int {
+	return len(b.ops)
+}
+
 // Write implements Batch.
 func (b *memDBBatch) Write() error {
 	if b.ops == nil {
@@ -90,5 +174,6 @@ func (b *memDBBatch) WriteSync() error {
 // Close implements Batch.
 func (b *memDBBatch) Close() error {
 	b.ops = nil
+	b.size = 0
 	return nil
 }
@@ -14,33 +14,51 @@ const (
 	chBufferSize = 64
 )
 
-// memDBIterator is a memDB iterator.
+// memDBIterator is a memDB iterator. It traverses the B-tree in a separate goroutine, which sends
+// items to the iterator over a channel.
 type memDBIterator struct {
-	ch     <-chan *item
-	cancel context.CancelFunc
-	item   *item
-	start  []byte
-	end    []byte
-	useMtx bool
+	db      *MemDB
+	ch      <-chan *item
+	cancel  context.CancelFunc
+	item    *item
+	start   []byte
+	end     []byte
+	reverse bool
+	useMtx  bool
 }
 
 var _ Iterator = (*memDBIterator)(nil)
 
-// newMemDBIterator creates a new memDBIterator.
+// newMemDBIterator creates a new memDBIterator over a snapshot of the database, such that writes
+// can be made to the database while iterating.
 func newMemDBIterator(db *MemDB, start []byte, end []byte, reverse bool) *memDBIterator {
-	return newMemDBIteratorMtxChoice(db, start, end, reverse, true)
+	db.mtx.Lock()
+	snapshot := &MemDB{btree: db.btree.Clone()}
+	db.mtx.Unlock()
+	return newMemDBIteratorMtxChoice(snapshot, start, end, reverse, false)
 }
 
 func newMemDBIteratorMtxChoice(db *MemDB, start []byte, end []byte, reverse bool, useMtx bool) *memDBIterator {
-	ctx, cancel := context.WithCancel(context.Background())
-	ch := make(chan *item, chBufferSize)
 	iter := &memDBIterator{
-		ch:     ch,
-		cancel: cancel,
-		start:  start,
-		end:    end,
-		useMtx: useMtx,
+		db:      db,
+		start:   start,
+		end:     end,
+		reverse: reverse,
+		useMtx:  useMtx,
 	}
+	iter.traverse(start, end)
+	return iter
+}
+
+// traverse starts a traversal goroutine over the range [start, end), and primes the iterator with
+// the first item.
+func (i *memDBIterator) traverse(start, end []byte) {
+	db, reverse, useMtx := i.db, i.reverse, i.useMtx
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan *item, chBufferSize)
+	i.ch = ch
+	i.cancel = cancel
+	i.item = nil
 
 	if useMtx {
 		db.mtx.RLock()
@@ -96,18 +114,28 @@ func newMemDBIteratorMtxChoice(db *MemDB, start []byte, end []byte, reverse bool
 
 	// prime the iterator with the first value, if any
 	if item, ok := <-ch; ok {
-		iter.item = item
+		i.item = item
 	}
+}
 
-	return iter
+// Seek implements Iterator. It stops the current traversal goroutine and starts a new one at the
+// given key, over the same B-tree.
+func (i *memDBIterator) Seek(key []byte) {
+	i.stop()
+	i.traverse(seekDomain(i.start, i.end, key, i.reverse))
 }
 
-// Close implements Iterator.
-func (i *memDBIterator) Close() error {
+// stop stops the traversal goroutine, if any.
+func (i *memDBIterator) stop() {
 	i.cancel()
 	for range i.ch { // drain channel
 	}
 	i.item = nil
+}
+
+// Close implements Iterator.
+func (i *memDBIterator) Close() error {
+	i.stop()
 	return nil
 }
 
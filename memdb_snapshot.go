@@ -0,0 +1,44 @@
+package db
+
+import "github.com/google/btree"
+
+// memDBSnapshot is a point-in-time view of a MemDB. It wraps a lazy copy-on-write clone of the
+// database's B-tree, so taking a snapshot is cheap, while subsequent writes to the database copy
+// the nodes they modify rather than changing the ones shared with the snapshot.
+type memDBSnapshot struct {
+	db *MemDB
+}
+
+var _ Snapshot = (*memDBSnapshot)(nil)
+
+// newMemDBSnapshot creates a new memDBSnapshot from a cloned B-tree, which must not be modified.
+func newMemDBSnapshot(tree *btree.BTree) *memDBSnapshot {
+	return &memDBSnapshot{
+		db: &MemDB{btree: tree},
+	}
+}
+
+// Get implements Snapshot.
+func (s *memDBSnapshot) Get(key []byte) ([]byte, error) {
+	return s.db.Get(key)
+}
+
+// Has implements Snapshot.
+func (s *memDBSnapshot) Has(key []byte) (bool, error) {
+	return s.db.Has(key)
+}
+
+// Iterator implements Snapshot.
+func (s *memDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	return s.db.Iterator(start, end)
+}
+
+// ReverseIterator implements Snapshot.
+func (s *memDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	return s.db.ReverseIterator(start, end)
+}
+
+// Close implements Snapshot.
+func (s *memDBSnapshot) Close() error {
+	return nil
+}
@@ -0,0 +1,141 @@
+package db
+
+import "bytes"
+
+// mergeIterator merges an iterator over pending writes with an iterator over the data they will
+// be written on top of (the parent). Pending deletions are represented by nil values, and are
+// skipped along with any parent item they shadow. Pending writes take precedence over parent
+// items with the same key.
+//
+// Both iterators must have the same domain and order.
+type mergeIterator struct {
+	parent  Iterator
+	pending Iterator
+	reverse bool
+}
+
+var _ Iterator = (*mergeIterator)(nil)
+
+func newMergeIterator(parent, pending Iterator, reverse bool) *mergeIterator {
+	return &mergeIterator{
+		parent:  parent,
+		pending: pending,
+		reverse: reverse,
+	}
+}
+
+// Domain implements Iterator.
+func (itr *mergeIterator) Domain() ([]byte, []byte) {
+	return itr.parent.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *mergeIterator) Valid() bool {
+	return itr.skipDeleted()
+}
+
+// Next implements Iterator.
+func (itr *mergeIterator) Next() {
+	itr.assertIsValid()
+	switch {
+	case !itr.pending.Valid():
+		itr.parent.Next()
+	case !itr.parent.Valid():
+		itr.pending.Next()
+	default:
+		switch itr.compare(itr.parent.Key(), itr.pending.Key()) {
+		case -1:
+			itr.parent.Next()
+		case 0:
+			itr.parent.Next()
+			itr.pending.Next()
+		default:
+			itr.pending.Next()
+		}
+	}
+}
+
+// Seek implements Iterator.
+func (itr *mergeIterator) Seek(key []byte) {
+	itr.parent.Seek(key)
+	itr.pending.Seek(key)
+}
+
+// Key implements Iterator.
+func (itr *mergeIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.current().Key()
+}
+
+// Value implements Iterator.
+func (itr *mergeIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.current().Value()
+}
+
+// Error implements Iterator.
+func (itr *mergeIterator) Error() error {
+	if err := itr.parent.Error(); err != nil {
+		return err
+	}
+	return itr.pending.Error()
+}
+
+// Close implements Iterator.
+func (itr *mergeIterator) Close() error {
+	err := itr.parent.Close()
+	if perr := itr.pending.Close(); err == nil {
+		err = perr
+	}
+	return err
+}
+
+// compare compares two keys in the order of iteration.
+func (itr *mergeIterator) compare(a, b []byte) int {
+	if itr.reverse {
+		return -bytes.Compare(a, b)
+	}
+	return bytes.Compare(a, b)
+}
+
+// current returns the iterator positioned at the current item. Both iterators must have been
+// advanced past any deletions by skipDeleted.
+func (itr *mergeIterator) current() Iterator {
+	switch {
+	case !itr.pending.Valid():
+		return itr.parent
+	case !itr.parent.Valid():
+		return itr.pending
+	case itr.compare(itr.parent.Key(), itr.pending.Key()) < 0:
+		return itr.parent
+	default:
+		return itr.pending
+	}
+}
+
+// skipDeleted advances the iterators past any pending deletions and the parent items they shadow,
+// and returns whether there is a current item.
+func (itr *mergeIterator) skipDeleted() bool {
+	for itr.pending.Valid() && itr.pending.Value() == nil {
+		if !itr.parent.Valid() {
+			itr.pending.Next()
+			continue
+		}
+		switch itr.compare(itr.parent.Key(), itr.pending.Key()) {
+		case -1:
+			return true // the parent item comes before the deletion
+		case 0:
+			itr.parent.Next()
+			itr.pending.Next()
+		default:
+			itr.pending.Next()
+		}
+	}
+	return itr.parent.Valid() || itr.pending.Valid()
+}
+
+func (itr *mergeIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
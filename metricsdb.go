@@ -0,0 +1,374 @@
+package db
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// Interval between updates of the database statistics exported by MetricsDB.
+	metricsDBStatsInterval = 15 * time.Second
+
+	// Namespace of the metrics exported by MetricsDB.
+	metricsDBNamespace = "tmdb"
+)
+
+// MetricsDB wraps a database, recording Prometheus metrics for its operations:
+//
+//   - tmdb_operation_duration_seconds: a histogram of operation latencies, whose count is the
+//     number of operations, labeled by operation. Iterator steps are recorded as iterator_next.
+//   - tmdb_operation_size_bytes: a histogram of the sizes of the keys and values read or written
+//     by an operation, labeled by operation. Iterator steps are not included.
+//   - tmdb_operation_errors_total: the number of failed operations, labeled by operation.
+//   - tmdb_open_iterators: the number of iterators that have not been closed.
+//   - tmdb_stat: the statistics returned by TypedStats, labeled by stat, where reported.
+//   - tmdb_backend_stat: the numeric values returned by Stats, labeled by key.
+//
+// Statistics are updated periodically until the database is closed. Snapshots and transactions
+// obtained from a MetricsDB are not instrumented.
+type MetricsDB struct {
+	db         DB
+	registry   prometheus.Registerer
+	collectors []prometheus.Collector
+
+	duration      *prometheus.HistogramVec
+	size          *prometheus.HistogramVec
+	errors        *prometheus.CounterVec
+	openIterators prometheus.Gauge
+	stats         *prometheus.GaugeVec
+	backendStats  *prometheus.GaugeVec
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ PrefixIteratorDB = (*MetricsDB)(nil)
+
+// NewMetricsDB wraps a database, registering its metrics with registry. The labels are added to
+// all metrics, and must distinguish the database from others registered with the same registry.
+func NewMetricsDB(db DB, registry prometheus.Registerer, labels prometheus.Labels) (*MetricsDB, error) {
+	mdb := &MetricsDB{
+		db:       db,
+		registry: registry,
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   metricsDBNamespace,
+			Name:        "operation_duration_seconds",
+			Help:        "Latency of database operations.",
+			ConstLabels: labels,
+			Buckets:     prometheus.ExponentialBuckets(1e-6, 4, 12),
+		}, []string{"op"}),
+		size: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   metricsDBNamespace,
+			Name:        "operation_size_bytes",
+			Help:        "Size of the keys and values read or written by database operations.",
+			ConstLabels: labels,
+			Buckets:     prometheus.ExponentialBuckets(16, 4, 10),
+		}, []string{"op"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsDBNamespace,
+			Name:        "operation_errors_total",
+			Help:        "Number of failed database operations.",
+			ConstLabels: labels,
+		}, []string{"op"}),
+		openIterators: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   metricsDBNamespace,
+			Name:        "open_iterators",
+			Help:        "Number of open database iterators.",
+			ConstLabels: labels,
+		}),
+		stats: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   metricsDBNamespace,
+			Name:        "stat",
+			Help:        "Database statistics.",
+			ConstLabels: labels,
+		}, []string{"stat"}),
+		backendStats: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   metricsDBNamespace,
+			Name:        "backend_stat",
+			Help:        "Numeric backend-specific database statistics.",
+			ConstLabels: labels,
+		}, []string{"key"}),
+		done: make(chan struct{}),
+	}
+	mdb.collectors = []prometheus.Collector{
+		mdb.duration, mdb.size, mdb.errors, mdb.openIterators, mdb.stats, mdb.backendStats,
+	}
+	for i, collector := range mdb.collectors {
+		if err := registry.Register(collector); err != nil {
+			for _, registered := range mdb.collectors[:i] {
+				registry.Unregister(registered)
+			}
+			return nil, err
+		}
+	}
+
+	mdb.updateStats()
+	go mdb.updateStatsPeriodically()
+	return mdb, nil
+}
+
+// updateStatsPeriodically updates the statistics until the database is closed.
+func (mdb *MetricsDB) updateStatsPeriodically() {
+	ticker := time.NewTicker(metricsDBStatsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			mdb.updateStats()
+		case <-mdb.done:
+			return
+		}
+	}
+}
+
+// updateStats updates the statistics gauges.
+func (mdb *MetricsDB) updateStats() {
+	stats := mdb.db.TypedStats()
+	for stat, value := range map[string]int64{
+		"approximate_keys":         stats.ApproximateKeys,
+		"disk_size_bytes":          stats.DiskSize,
+		"memtable_size_bytes":      stats.MemtableSize,
+		"cache_hits":               stats.CacheHits,
+		"cache_misses":             stats.CacheMisses,
+		"compaction_pending_bytes": stats.CompactionPendingBytes,
+	} {
+		if value >= 0 {
+			mdb.stats.WithLabelValues(stat).Set(float64(value))
+		}
+	}
+	for key, str := range mdb.db.Stats() {
+		if value, err := strconv.ParseFloat(str, 64); err == nil {
+			mdb.backendStats.WithLabelValues(key).Set(value)
+		}
+	}
+}
+
+// observe records an operation that started at start. Negative sizes are not recorded.
+func (mdb *MetricsDB) observe(op OpKind, start time.Time, size int, err error) {
+	mdb.duration.WithLabelValues(string(op)).Observe(time.Since(start).Seconds())
+	if err != nil {
+		mdb.errors.WithLabelValues(string(op)).Inc()
+		return
+	}
+	if size >= 0 {
+		mdb.size.WithLabelValues(string(op)).Observe(float64(size))
+	}
+}
+
+// Get implements DB.
+func (mdb *MetricsDB) Get(key []byte) ([]byte, error) {
+	start := time.Now()
+	value, err := mdb.db.Get(key)
+	mdb.observe(OpGet, start, len(key)+len(value), err)
+	return value, err
+}
+
+// Has implements DB.
+func (mdb *MetricsDB) Has(key []byte) (bool, error) {
+	start := time.Now()
+	ok, err := mdb.db.Has(key)
+	mdb.observe(OpHas, start, len(key), err)
+	return ok, err
+}
+
+// MultiGet implements DB.
+func (mdb *MetricsDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	start := time.Now()
+	values, err := mdb.db.MultiGet(keys)
+	size := 0
+	for i := range keys {
+		size += len(keys[i])
+		if i < len(values) {
+			size += len(values[i])
+		}
+	}
+	mdb.observe(OpMultiGet, start, size, err)
+	return values, err
+}
+
+// Set implements DB.
+func (mdb *MetricsDB) Set(key, value []byte) error {
+	start := time.Now()
+	err := mdb.db.Set(key, value)
+	mdb.observe(OpSet, start, len(key)+len(value), err)
+	return err
+}
+
+// SetSync implements DB.
+func (mdb *MetricsDB) SetSync(key, value []byte) error {
+	start := time.Now()
+	err := mdb.db.SetSync(key, value)
+	mdb.observe(OpSetSync, start, len(key)+len(value), err)
+	return err
+}
+
+// Delete implements DB.
+func (mdb *MetricsDB) Delete(key []byte) error {
+	start := time.Now()
+	err := mdb.db.Delete(key)
+	mdb.observe(OpDelete, start, len(key), err)
+	return err
+}
+
+// DeleteSync implements DB.
+func (mdb *MetricsDB) DeleteSync(key []byte) error {
+	start := time.Now()
+	err := mdb.db.DeleteSync(key)
+	mdb.observe(OpDeleteSync, start, len(key), err)
+	return err
+}
+
+// DeleteRange implements DB.
+func (mdb *MetricsDB) DeleteRange(start, end []byte) error {
+	began := time.Now()
+	err := mdb.db.DeleteRange(start, end)
+	mdb.observe(OpDeleteRange, began, len(start)+len(end), err)
+	return err
+}
+
+// Iterator implements DB.
+func (mdb *MetricsDB) Iterator(start, end []byte) (Iterator, error) {
+	return mdb.newIterator(mdb.db.Iterator(start, end))
+}
+
+// ReverseIterator implements DB.
+func (mdb *MetricsDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return mdb.newIterator(mdb.db.ReverseIterator(start, end))
+}
+
+// IteratorWithOptions implements DB.
+func (mdb *MetricsDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	return mdb.newIterator(mdb.db.IteratorWithOptions(start, end, opts))
+}
+
+// IteratePrefix implements PrefixIteratorDB.
+func (mdb *MetricsDB) IteratePrefix(prefix []byte) (Iterator, error) {
+	return mdb.newIterator(IteratePrefix(mdb.db, prefix))
+}
+
+// ReverseIteratePrefix implements PrefixIteratorDB.
+func (mdb *MetricsDB) ReverseIteratePrefix(prefix []byte) (Iterator, error) {
+	return mdb.newIterator(ReverseIteratePrefix(mdb.db, prefix))
+}
+
+// newIterator wraps an iterator returned by the underlying database.
+func (mdb *MetricsDB) newIterator(itr Iterator, err error) (Iterator, error) {
+	if err != nil {
+		return nil, err
+	}
+	mdb.openIterators.Inc()
+	return &metricsIterator{Iterator: itr, mdb: mdb}, nil
+}
+
+// Close implements DB. It stops updating the statistics and unregisters the metrics, before
+// closing the underlying database.
+func (mdb *MetricsDB) Close() error {
+	mdb.closeOnce.Do(func() {
+		close(mdb.done)
+		for _, collector := range mdb.collectors {
+			mdb.registry.Unregister(collector)
+		}
+	})
+	return mdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (mdb *MetricsDB) NewBatch() Batch {
+	return &metricsBatch{Batch: mdb.db.NewBatch(), mdb: mdb}
+}
+
+// Print implements DB.
+func (mdb *MetricsDB) Print() error {
+	return mdb.db.Print()
+}
+
+// Stats implements DB.
+func (mdb *MetricsDB) Stats() map[string]string {
+	return mdb.db.Stats()
+}
+
+// TypedStats implements DB.
+func (mdb *MetricsDB) TypedStats() DBStats {
+	return mdb.db.TypedStats()
+}
+
+// Capabilities implements DB.
+func (mdb *MetricsDB) Capabilities() Capabilities {
+	return mdb.db.Capabilities()
+}
+
+// Snapshot implements DB.
+func (mdb *MetricsDB) Snapshot() (Snapshot, error) {
+	return mdb.db.Snapshot()
+}
+
+// NewTxn implements DB.
+func (mdb *MetricsDB) NewTxn() (Txn, error) {
+	return mdb.db.NewTxn()
+}
+
+// Checkpoint implements DB.
+func (mdb *MetricsDB) Checkpoint(dir string) error {
+	return mdb.db.Checkpoint(dir)
+}
+
+// metricsBatch records the writes of a batch.
+type metricsBatch struct {
+	Batch
+	mdb *MetricsDB
+}
+
+var _ Batch = (*metricsBatch)(nil)
+
+// Write implements Batch.
+func (b *metricsBatch) Write() error {
+	size, _ := b.GetByteSize()
+	start := time.Now()
+	err := b.Batch.Write()
+	b.mdb.observe(OpBatchWrite, start, size, err)
+	return err
+}
+
+// WriteSync implements Batch.
+func (b *metricsBatch) WriteSync() error {
+	size, _ := b.GetByteSize()
+	start := time.Now()
+	err := b.Batch.WriteSync()
+	b.mdb.observe(OpBatchWriteSync, start, size, err)
+	return err
+}
+
+// metricsIterator records the steps of an iterator.
+type metricsIterator struct {
+	Iterator
+	mdb    *MetricsDB
+	closed bool
+}
+
+var _ Iterator = (*metricsIterator)(nil)
+
+// Next implements Iterator.
+func (itr *metricsIterator) Next() {
+	start := time.Now()
+	itr.Iterator.Next()
+	itr.mdb.observe(OpIteratorNext, start, -1, nil)
+}
+
+// Seek implements Iterator.
+func (itr *metricsIterator) Seek(key []byte) {
+	start := time.Now()
+	itr.Iterator.Seek(key)
+	itr.mdb.observe(OpIteratorSeek, start, -1, nil)
+}
+
+// Close implements Iterator.
+func (itr *metricsIterator) Close() error {
+	if !itr.closed {
+		itr.closed = true
+		itr.mdb.openIterators.Dec()
+	}
+	return itr.Iterator.Close()
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsDB(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mdb, err := NewMetricsDB(NewMemDB(), registry, prometheus.Labels{"db": "test"})
+	require.NoError(t, err)
+
+	// A second database with the same labels can't be registered.
+	_, err = NewMetricsDB(NewMemDB(), registry, prometheus.Labels{"db": "test"})
+	require.Error(t, err)
+
+	require.NoError(t, mdb.Set([]byte("a"), []byte{1, 2, 3}))
+	require.NoError(t, mdb.SetSync([]byte("b"), []byte{1}))
+	value, err := mdb.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, value)
+	_, err = mdb.Get(nil)
+	require.Error(t, err)
+
+	batch := mdb.NewBatch()
+	require.NoError(t, batch.Set([]byte("c"), []byte{1, 2}))
+	require.NoError(t, batch.Delete([]byte("b")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	itr, err := mdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, testutil.ToFloat64(mdb.openIterators))
+	for ; itr.Valid(); itr.Next() {
+	}
+	require.NoError(t, itr.Close())
+	require.NoError(t, itr.Close())
+	require.EqualValues(t, 0, testutil.ToFloat64(mdb.openIterators))
+
+	require.EqualValues(t, 1, testutil.ToFloat64(mdb.errors.WithLabelValues(string(OpGet))))
+	require.Equal(t, 4, testutil.CollectAndCount(mdb.size, "tmdb_operation_size_bytes"))
+
+	durations := map[string]uint64{}
+	metrics, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range metrics {
+		if family.GetName() != "tmdb_operation_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			require.Equal(t, "test", labels["db"])
+			durations[labels["op"]] = metric.GetHistogram().GetSampleCount()
+		}
+	}
+	require.Equal(t, map[string]uint64{
+		string(OpSet):          1,
+		string(OpSetSync):      1,
+		string(OpGet):          2,
+		string(OpBatchWrite):   1,
+		string(OpIteratorNext): 2,
+	}, durations)
+
+	// Statistics are exported as gauges.
+	mdb.updateStats()
+	require.EqualValues(t, 2, testutil.ToFloat64(mdb.stats.WithLabelValues("approximate_keys")))
+	require.EqualValues(t, 2, testutil.ToFloat64(mdb.backendStats.WithLabelValues("database.size")))
+
+	// Closing the database unregisters the metrics, so they can be registered again.
+	require.NoError(t, mdb.Close())
+	mdb, err = NewMetricsDB(NewMemDB(), registry, prometheus.Labels{"db": "test"})
+	require.NoError(t, err)
+	require.NoError(t, mdb.Close())
+}
@@ -0,0 +1,230 @@
+package db
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+)
+
+const (
+	// Default size of the batches written by Migrate.
+	migrateDefaultBatchSize = 4 << 20
+)
+
+// MigrateOptions configures Migrate.
+type MigrateOptions struct {
+	// BatchSize is the approximate size of the keys and values written per batch, in bytes.
+	// Defaults to 4 MB if less than or equal to 0.
+	BatchSize int
+
+	// CursorKey is a key in the destination database where the last migrated key is stored in
+	// each batch, so that an interrupted migration can be resumed by calling Migrate again with
+	// the same options. The key is deleted once all items have been copied, and it must not exist
+	// in the source database. If nil, the migration always starts from the beginning.
+	CursorKey []byte
+
+	// Progress is called after each batch is written, and periodically during verification.
+	Progress func(MigrateProgress)
+
+	// Verify runs a verification pass after copying, which compares hashes of all keys and
+	// values in the source and destination databases.
+	Verify bool
+}
+
+// MigrateProgress reports the progress of Migrate.
+type MigrateProgress struct {
+	// Verifying is true during the verification pass.
+	Verifying bool
+
+	// Keys is the number of keys copied, or verified in the source database, by this call to
+	// Migrate. It does not include keys copied before resuming.
+	Keys int64
+
+	// Bytes is the size of the keys and values counted by Keys.
+	Bytes int64
+
+	// LastKey is the last key copied or verified.
+	LastKey []byte
+}
+
+// Migrate copies all items from the source database to the destination database in batches, e.g.
+// to switch a node to a different backend. The destination database should be empty, except for
+// the items of an interrupted migration being resumed via MigrateOptions.CursorKey.
+//
+// Items are read from an iterator, and thus from an implicit snapshot of the source database.
+// Writes to the source database made after that are not migrated, so it should not be written to
+// during the migration, but such writes are detected by the verification pass if enabled.
+//
+// The cursor is written last in each batch, so that it never refers to a key which hasn't been
+// written, even on backends where batches are not atomic.
+func Migrate(src, dst DB, opts MigrateOptions) error {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = migrateDefaultBatchSize
+	}
+	if opts.CursorKey != nil && len(opts.CursorKey) == 0 {
+		return errKeyEmpty
+	}
+
+	if err := migrateCopy(src, dst, opts); err != nil {
+		return err
+	}
+	if opts.Verify {
+		return migrateVerify(src, dst, opts)
+	}
+	return nil
+}
+
+// migrateCopy copies the items from src to dst, resuming after the cursor if there is one.
+func migrateCopy(src, dst DB, opts MigrateOptions) error {
+	var start []byte
+	if opts.CursorKey != nil {
+		cursor, err := dst.Get(opts.CursorKey)
+		if err != nil {
+			return err
+		}
+		if cursor != nil {
+			start = append(cp(cursor), 0) // the key after the cursor
+		}
+	}
+
+	itr, err := src.Iterator(start, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+
+	var (
+		progress MigrateProgress
+		batch    = dst.NewBatch()
+		size     int
+	)
+	defer func() {
+		batch.Close()
+	}()
+	write := func(lastKey []byte) error {
+		if opts.CursorKey != nil {
+			if err := batch.Set(opts.CursorKey, lastKey); err != nil {
+				return err
+			}
+		}
+		if err := batch.WriteSync(); err != nil {
+			return err
+		}
+		if err := batch.Close(); err != nil {
+			return err
+		}
+		batch = dst.NewBatch()
+		size = 0
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+		return nil
+	}
+
+	for ; itr.Valid(); itr.Next() {
+		key, value := itr.Key(), itr.Value()
+		if opts.CursorKey != nil && bytes.Equal(key, opts.CursorKey) {
+			return fmt.Errorf("migration cursor key %X exists in the source database", key)
+		}
+		if err = batch.Set(key, value); err != nil {
+			return err
+		}
+		size += len(key) + len(value)
+		progress.Keys++
+		progress.Bytes += int64(len(key) + len(value))
+		progress.LastKey = cp(key)
+		if size >= opts.BatchSize {
+			if err = write(progress.LastKey); err != nil {
+				return err
+			}
+		}
+	}
+	if err = itr.Error(); err != nil {
+		return err
+	}
+	if size > 0 {
+		if err = write(progress.LastKey); err != nil {
+			return err
+		}
+	}
+	if opts.CursorKey != nil {
+		return dst.DeleteSync(opts.CursorKey)
+	}
+	return nil
+}
+
+// migrateVerify compares hashes of the items in src and dst. The cursor key is skipped in dst, in
+// case it is left over from an earlier migration, but not in src, where it would not have been
+// migrated.
+func migrateVerify(src, dst DB, opts MigrateOptions) error {
+	srcItr, err := src.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer srcItr.Close()
+	dstItr, err := dst.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer dstItr.Close()
+
+	var (
+		progress = MigrateProgress{Verifying: true}
+		srcHash  = sha256.New()
+		dstHash  = sha256.New()
+		srcKeys  int64
+		dstKeys  int64
+		size     int
+	)
+	for srcItr.Valid() || dstItr.Valid() {
+		if srcItr.Valid() {
+			key, value := srcItr.Key(), srcItr.Value()
+			hashItem(srcHash, key, value)
+			srcKeys++
+			size += len(key) + len(value)
+			progress.Keys++
+			progress.Bytes += int64(len(key) + len(value))
+			progress.LastKey = cp(key)
+			srcItr.Next()
+		}
+		if dstItr.Valid() {
+			key := dstItr.Key()
+			if opts.CursorKey == nil || !bytes.Equal(key, opts.CursorKey) {
+				hashItem(dstHash, key, dstItr.Value())
+				dstKeys++
+			}
+			dstItr.Next()
+		}
+		if size >= opts.BatchSize && opts.Progress != nil {
+			opts.Progress(progress)
+			size = 0
+		}
+	}
+	if err = srcItr.Error(); err != nil {
+		return err
+	}
+	if err = dstItr.Error(); err != nil {
+		return err
+	}
+	if size > 0 && opts.Progress != nil {
+		opts.Progress(progress)
+	}
+
+	srcSum, dstSum := srcHash.Sum(nil), dstHash.Sum(nil)
+	if srcKeys != dstKeys || !bytes.Equal(srcSum, dstSum) {
+		return fmt.Errorf("migration verification failed: source has %v keys with hash %X, "+
+			"destination has %v keys with hash %X", srcKeys, srcSum, dstKeys, dstSum)
+	}
+	return nil
+}
+
+// hashItem adds a key/value pair to a hash, with length prefixes.
+func hashItem(h hash.Hash, key, value []byte) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	for _, b := range [][]byte{key, value} {
+		h.Write(buf[:binary.PutUvarint(buf, uint64(len(b)))])
+		h.Write(b)
+	}
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// failingBatchDB is a MemDB whose batch writes fail after a number of successful writes.
+type failingBatchDB struct {
+	*MemDB
+	writes    int
+	failAfter int
+}
+
+func (db *failingBatchDB) NewBatch() Batch {
+	return &failingBatch{Batch: db.MemDB.NewBatch(), db: db}
+}
+
+type failingBatch struct {
+	Batch
+	db *failingBatchDB
+}
+
+func (b *failingBatch) WriteSync() error {
+	if b.db.writes >= b.db.failAfter {
+		return errors.New("write failed")
+	}
+	b.db.writes++
+	return b.Batch.WriteSync()
+}
+
+func TestMigrate(t *testing.T) {
+	src := NewMemDB()
+	for i := int64(0); i < 100; i++ {
+		require.NoError(t, src.Set(int642Bytes(i), []byte{byte(i)}))
+	}
+	dst := NewMemDB()
+
+	var progress []MigrateProgress
+	err := Migrate(src, dst, MigrateOptions{
+		BatchSize: 90, // 10 items per batch
+		Progress:  func(p MigrateProgress) { progress = append(progress, p) },
+		Verify:    true,
+	})
+	require.NoError(t, err)
+	require.Len(t, progress, 20)
+	require.Equal(t, MigrateProgress{Keys: 10, Bytes: 90, LastKey: int642Bytes(9)}, progress[0])
+	require.Equal(t, MigrateProgress{Keys: 100, Bytes: 900, LastKey: int642Bytes(99)}, progress[9])
+	require.Equal(t, MigrateProgress{Verifying: true, Keys: 100, Bytes: 900, LastKey: int642Bytes(99)},
+		progress[19])
+
+	itr, err := dst.Iterator(nil, nil)
+	require.NoError(t, err)
+	expect := make([]int64, 100)
+	for i := range expect {
+		expect[i] = int64(i)
+	}
+	verifyIterator(t, itr, expect, "migrated")
+
+	// Verification detects differences between the databases.
+	require.NoError(t, dst.Set([]byte("extra"), []byte{1}))
+	err = Migrate(src, dst, MigrateOptions{Verify: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "source has 100 keys")
+	require.Contains(t, err.Error(), "destination has 101 keys")
+}
+
+func TestMigrateResume(t *testing.T) {
+	cursorKey := []byte("migrate_cursor")
+	src := NewMemDB()
+	for i := int64(0); i < 100; i++ {
+		require.NoError(t, src.Set(int642Bytes(i), []byte{byte(i)}))
+	}
+	dst := &failingBatchDB{MemDB: NewMemDB(), failAfter: 3}
+
+	opts := MigrateOptions{BatchSize: 90, CursorKey: cursorKey, Verify: true}
+	require.Error(t, Migrate(src, dst, opts))
+	cursor, err := dst.Get(cursorKey)
+	require.NoError(t, err)
+	require.Equal(t, int642Bytes(29), cursor)
+
+	// Resuming copies the remaining items, and removes the cursor.
+	dst.failAfter = 100
+	var progress MigrateProgress
+	opts.Progress = func(p MigrateProgress) {
+		if !p.Verifying {
+			progress = p
+		}
+	}
+	require.NoError(t, Migrate(src, dst, opts))
+	require.EqualValues(t, 70, progress.Keys)
+	ok, err := dst.Has(cursorKey)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	itr, err := dst.Iterator(nil, nil)
+	require.NoError(t, err)
+	expect := make([]int64, 100)
+	for i := range expect {
+		expect[i] = int64(i)
+	}
+	verifyIterator(t, itr, expect, "migrated")
+
+	require.Equal(t, errKeyEmpty, Migrate(src, dst, MigrateOptions{CursorKey: []byte{}}))
+
+	// The cursor key can't be migrated, so it must not exist in the source database.
+	require.NoError(t, src.Set(cursorKey, []byte{1}))
+	err = Migrate(src, NewMemDB(), MigrateOptions{CursorKey: cursorKey})
+	require.EqualError(t, err, "migration cursor key 6D6967726174655F637572736F72 exists in the source database")
+
+	// Verification doesn't skip it in the source database either, so it can't be lost silently.
+	err = migrateVerify(src, dst, MigrateOptions{BatchSize: 90, CursorKey: cursorKey})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "source has 101 keys")
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Options are backend-specific database options passed to NewDBWithOptions, e.g. as read from a
+// configuration file. Get returns the value of the option with the given key, or nil if it is not
+// set. Besides OptionsMap, it is implemented by common configuration libraries such as viper.
+type Options interface {
+	Get(key string) interface{}
+}
+
+// OptionsMap implements Options using a map.
+type OptionsMap map[string]interface{}
+
+var _ Options = OptionsMap(nil)
+
+// Get implements Options.
+func (m OptionsMap) Get(key string) interface{} {
+	return m[key]
+}
+
+// optInt returns the value of an integer option, or def if it is not set. Besides Go integer types,
+// it accepts integral floats and strings, as decoded from configuration files.
+func optInt(opts Options, key string, def int) (int, error) {
+	if opts == nil {
+		return def, nil
+	}
+	switch v := opts.Get(key).(type) {
+	case nil:
+		return def, nil
+	case int:
+		return v, nil
+	case int8:
+		return int(v), nil
+	case int16:
+		return int(v), nil
+	case int32:
+		return int(v), nil
+	case int64:
+		return int(v), nil
+	case uint:
+		return int(v), nil
+	case uint8:
+		return int(v), nil
+	case uint16:
+		return int(v), nil
+	case uint32:
+		return int(v), nil
+	case uint64:
+		return int(v), nil
+	case float32:
+		if v == float32(math.Trunc(float64(v))) {
+			return int(v), nil
+		}
+	case float64:
+		if v == math.Trunc(v) {
+			return int(v), nil
+		}
+	case string:
+		if i, err := strconv.Atoi(v); err == nil {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("option %s must be an integer, got %v", key, opts.Get(key))
+}
+
+// optBool returns the value of a boolean option, or def if it is not set. Besides bool, it accepts
+// strings, as decoded from configuration files.
+func optBool(opts Options, key string, def bool) (bool, error) {
+	if opts == nil {
+		return def, nil
+	}
+	switch v := opts.Get(key).(type) {
+	case nil:
+		return def, nil
+	case bool:
+		return v, nil
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b, nil
+		}
+	}
+	return false, fmt.Errorf("option %s must be a boolean, got %v", key, opts.Get(key))
+}
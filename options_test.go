@@ -0,0 +1,48 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions(t *testing.T) {
+	opts := OptionsMap{
+		"int":     42,
+		"int64":   int64(42),
+		"uint32":  uint32(42),
+		"float":   42.0,
+		"string":  "42",
+		"bool":    true,
+		"boolstr": "false",
+		"invalid": 4.2,
+	}
+
+	for _, key := range []string{"int", "int64", "uint32", "float", "string"} {
+		value, err := optInt(opts, key, 7)
+		require.NoError(t, err, key)
+		require.Equal(t, 42, value, key)
+	}
+	value, err := optInt(opts, "unset", 7)
+	require.NoError(t, err)
+	require.Equal(t, 7, value)
+	value, err = optInt(nil, "int", 7)
+	require.NoError(t, err)
+	require.Equal(t, 7, value)
+	_, err = optInt(opts, "invalid", 7)
+	require.Error(t, err)
+	_, err = optInt(opts, "bool", 7)
+	require.Error(t, err)
+
+	b, err := optBool(opts, "bool", false)
+	require.NoError(t, err)
+	require.True(t, b)
+	b, err = optBool(opts, "boolstr", true)
+	require.NoError(t, err)
+	require.False(t, b)
+	b, err = optBool(opts, "unset", true)
+	require.NoError(t, err)
+	require.True(t, b)
+	_, err = optBool(opts, "int", false)
+	require.Error(t, err)
+}
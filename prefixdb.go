@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"sync"
 )
@@ -12,7 +13,10 @@ type PrefixDB struct {
 	db     DB
 }
 
-var _ DB = (*PrefixDB)(nil)
+var (
+	_ ContextDB        = (*PrefixDB)(nil)
+	_ PrefixIteratorDB = (*PrefixDB)(nil)
+)
 
 // NewPrefixDB lets you namespace multiple DBs within a single DB.
 func NewPrefixDB(db DB, prefix []byte) *PrefixDB {
@@ -50,6 +54,23 @@ func (pdb *PrefixDB) Has(key []byte) (bool, error) {
 	return ok, nil
 }
 
+// WithContext implements ContextDB, binding the underlying database to the context.
+func (pdb *PrefixDB) WithContext(ctx context.Context) DB {
+	return NewPrefixDB(WithContext(ctx, pdb.db), pdb.prefix)
+}
+
+// MultiGet implements DB.
+func (pdb *PrefixDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	pkeys := make([][]byte, len(keys))
+	for i, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+		pkeys[i] = pdb.prefixed(key)
+	}
+	return pdb.db.MultiGet(pkeys)
+}
+
 // Set implements DB.
 func (pdb *PrefixDB) Set(key []byte, value []byte) error {
 	if len(key) == 0 {
@@ -96,19 +117,23 @@ func (pdb *PrefixDB) DeleteSync(key []byte) error {
 	return pdb.db.DeleteSync(pdb.prefixed(key))
 }
 
+// DeleteRange implements DB.
+func (pdb *PrefixDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+
+	pstart, pend := prefixDomain(pdb.prefix, start, end)
+	return pdb.db.DeleteRange(pstart, pend)
+}
+
 // Iterator implements DB.
 func (pdb *PrefixDB) Iterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
 
-	var pstart, pend []byte
-	pstart = append(cp(pdb.prefix), start...)
-	if end == nil {
-		pend = cpIncr(pdb.prefix)
-	} else {
-		pend = append(cp(pdb.prefix), end...)
-	}
+	pstart, pend := prefixDomain(pdb.prefix, start, end)
 	itr, err := pdb.db.Iterator(pstart, pend)
 	if err != nil {
 		return nil, err
@@ -123,13 +148,7 @@ func (pdb *PrefixDB) ReverseIterator(start, end []byte) (Iterator, error) {
 		return nil, errKeyEmpty
 	}
 
-	var pstart, pend []byte
-	pstart = append(cp(pdb.prefix), start...)
-	if end == nil {
-		pend = cpIncr(pdb.prefix)
-	} else {
-		pend = append(cp(pdb.prefix), end...)
-	}
+	pstart, pend := prefixDomain(pdb.prefix, start, end)
 	ritr, err := pdb.db.ReverseIterator(pstart, pend)
 	if err != nil {
 		return nil, err
@@ -138,6 +157,66 @@ func (pdb *PrefixDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	return newPrefixIterator(pdb.prefix, start, end, ritr)
 }
 
+// IteratorWithOptions implements DB.
+func (pdb *PrefixDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+
+	pstart, pend := prefixDomain(pdb.prefix, start, end)
+	itr, err := pdb.db.IteratorWithOptions(pstart, pend, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPrefixIterator(pdb.prefix, start, end, itr)
+}
+
+// IteratePrefix implements PrefixIteratorDB, using the source database's native prefix iteration
+// if any.
+func (pdb *PrefixDB) IteratePrefix(prefix []byte) (Iterator, error) {
+	itr, err := IteratePrefix(pdb.db, pdb.prefixed(prefix))
+	if err != nil {
+		return nil, err
+	}
+	start, end := prefixRange(prefix)
+	return newPrefixIterator(pdb.prefix, start, end, itr)
+}
+
+// ReverseIteratePrefix implements PrefixIteratorDB.
+func (pdb *PrefixDB) ReverseIteratePrefix(prefix []byte) (Iterator, error) {
+	ritr, err := ReverseIteratePrefix(pdb.db, pdb.prefixed(prefix))
+	if err != nil {
+		return nil, err
+	}
+	start, end := prefixRange(prefix)
+	return newPrefixIterator(pdb.prefix, start, end, ritr)
+}
+
+// Snapshot implements DB.
+func (pdb *PrefixDB) Snapshot() (Snapshot, error) {
+	snapshot, err := pdb.db.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return newPrefixSnapshot(pdb.prefix, snapshot), nil
+}
+
+// NewTxn implements DB.
+func (pdb *PrefixDB) NewTxn() (Txn, error) {
+	txn, err := pdb.db.NewTxn()
+	if err != nil {
+		return nil, err
+	}
+	return newPrefixTxn(pdb.prefix, txn), nil
+}
+
+// Checkpoint implements DB. It checkpoints the whole underlying database, including keys outside
+// the prefix, so the copy must be opened with NewPrefixDB using the same prefix.
+func (pdb *PrefixDB) Checkpoint(dir string) error {
+	return pdb.db.Checkpoint(dir)
+}
+
 // NewBatch implements DB.
 func (pdb *PrefixDB) NewBatch() Batch {
 	return newPrefixBatch(pdb.prefix, pdb.db.NewBatch())
@@ -180,6 +259,32 @@ func (pdb *PrefixDB) Stats() map[string]string {
 	return stats
 }
 
+// TypedStats implements DB. The statistics are those of the source database, except that the
+// number of keys within the prefix is unknown.
+func (pdb *PrefixDB) TypedStats() DBStats {
+	stats := pdb.db.TypedStats()
+	stats.ApproximateKeys = -1
+	return stats
+}
+
+// Capabilities implements DB.
+func (pdb *PrefixDB) Capabilities() Capabilities {
+	return pdb.db.Capabilities()
+}
+
 func (pdb *PrefixDB) prefixed(key []byte) []byte {
 	return append(cp(pdb.prefix), key...)
 }
+
+// prefixDomain returns the domain of the underlying database covering the given domain within
+// the prefix namespace. It never extends past the namespace, since a nil end is the end of the
+// prefix domain.
+func prefixDomain(prefix, start, end []byte) (pstart, pend []byte) {
+	pstart = append(cp(prefix), start...)
+	if end == nil {
+		pend = PrefixEndBytes(prefix)
+	} else {
+		pend = append(cp(prefix), end...)
+	}
+	return pstart, pend
+}
@@ -35,6 +35,25 @@ func (pb prefixDBBatch) Delete(key []byte) error {
 	return pb.source.Delete(pkey)
 }
 
+// DeleteRange implements Batch.
+func (pb prefixDBBatch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	pstart, pend := prefixDomain(pb.prefix, start, end)
+	return pb.source.DeleteRange(pstart, pend)
+}
+
+// GetByteSize implements Batch.
+func (pb prefixDBBatch) GetByteSize() (int, error) {
+	return pb.source.GetByteSize()
+}
+
+// Len implements Batch.
+func (pb prefixDBBatch) Len() int {
+	return pb.source.Len()
+}
+
 // Write implements Batch.
 func (pb prefixDBBatch) Write() error {
 	return pb.source.Write()
@@ -6,17 +6,25 @@ import (
 )
 
 // IteratePrefix is a convenience function for iterating over a key domain
-// restricted by prefix.
+// restricted by prefix. It uses the database's native prefix iteration if
+// it implements PrefixIteratorDB.
 func IteratePrefix(db DB, prefix []byte) (Iterator, error) {
-	var start, end []byte
-	if len(prefix) == 0 {
-		start = nil
-		end = nil
-	} else {
-		start = cp(prefix)
-		end = cpIncr(prefix)
+	if pdb, ok := db.(PrefixIteratorDB); ok {
+		return pdb.IteratePrefix(prefix)
 	}
-	itr, err := db.Iterator(start, end)
+	itr, err := db.Iterator(prefixRange(prefix))
+	if err != nil {
+		return nil, err
+	}
+	return itr, nil
+}
+
+// ReverseIteratePrefix is like IteratePrefix, but iterates in descending order.
+func ReverseIteratePrefix(db DB, prefix []byte) (Iterator, error) {
+	if pdb, ok := db.(PrefixIteratorDB); ok {
+		return pdb.ReverseIteratePrefix(prefix)
+	}
+	itr, err := db.ReverseIterator(prefixRange(prefix))
 	if err != nil {
 		return nil, err
 	}
@@ -98,7 +106,17 @@ func (itr *prefixDBIterator) Next() {
 	}
 }
 
-// Next implements Iterator.
+// Seek implements Iterator.
+func (itr *prefixDBIterator) Seek(key []byte) {
+	itr.source.Seek(append(cp(itr.prefix), key...))
+	itr.valid = itr.source.Valid() && bytes.HasPrefix(itr.source.Key(), itr.prefix)
+	if itr.valid && bytes.Equal(itr.source.Key(), itr.prefix) {
+		// Skip a key exactly matching the prefix, as in Next.
+		itr.Next()
+	}
+}
+
+// Key implements Iterator.
 func (itr *prefixDBIterator) Key() []byte {
 	itr.assertIsValid()
 	key := itr.source.Key()
@@ -0,0 +1,67 @@
+package db
+
+// prefixDBSnapshot wraps a snapshot of the underlying database, restricted to a prefix namespace.
+type prefixDBSnapshot struct {
+	prefix []byte
+	source Snapshot
+}
+
+var _ Snapshot = (*prefixDBSnapshot)(nil)
+
+func newPrefixSnapshot(prefix []byte, source Snapshot) *prefixDBSnapshot {
+	return &prefixDBSnapshot{
+		prefix: prefix,
+		source: source,
+	}
+}
+
+// Get implements Snapshot.
+func (ps *prefixDBSnapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	return ps.source.Get(append(cp(ps.prefix), key...))
+}
+
+// Has implements Snapshot.
+func (ps *prefixDBSnapshot) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	return ps.source.Has(append(cp(ps.prefix), key...))
+}
+
+// Iterator implements Snapshot.
+func (ps *prefixDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+
+	pstart, pend := prefixDomain(ps.prefix, start, end)
+	itr, err := ps.source.Iterator(pstart, pend)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPrefixIterator(ps.prefix, start, end, itr)
+}
+
+// ReverseIterator implements Snapshot.
+func (ps *prefixDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+
+	pstart, pend := prefixDomain(ps.prefix, start, end)
+	ritr, err := ps.source.ReverseIterator(pstart, pend)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPrefixIterator(ps.prefix, start, end, ritr)
+}
+
+// Close implements Snapshot.
+func (ps *prefixDBSnapshot) Close() error {
+	return ps.source.Close()
+}
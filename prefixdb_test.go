@@ -224,3 +224,27 @@ func TestPrefixDBReverseIterator7(t *testing.T) {
 	checkInvalid(t, itr)
 	itr.Close()
 }
+
+func TestPrefixDBTrailingFF(t *testing.T) {
+	db := NewMemDB()
+	for _, key := range [][]byte{{0x01, 0xff, 0x01}, {0x01, 0xff, 0x02}, {0x02}, {0x02, 0x00}} {
+		require.NoError(t, db.Set(key, []byte{1}))
+	}
+	pdb := NewPrefixDB(db, []byte{0x01, 0xff})
+
+	itr, err := pdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, []byte{0x01}, []byte{1})
+	checkNext(t, itr, true)
+	checkItem(t, itr, []byte{0x02}, []byte{1})
+	checkNext(t, itr, false)
+	itr.Close()
+
+	// Deleting the whole namespace leaves the keys following it alone.
+	require.NoError(t, pdb.DeleteRange(nil, nil))
+	batch := pdb.NewBatch()
+	require.NoError(t, batch.DeleteRange(nil, nil))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	assertKeyValues(t, db, map[string][]byte{"\x02": {1}, "\x02\x00": {1}})
+}
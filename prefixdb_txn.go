@@ -0,0 +1,98 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+)
+
+// prefixDBTxn wraps a transaction on the underlying database, restricted to a prefix namespace.
+type prefixDBTxn struct {
+	prefix []byte
+	source Txn
+}
+
+var _ Txn = (*prefixDBTxn)(nil)
+
+func newPrefixTxn(prefix []byte, source Txn) *prefixDBTxn {
+	return &prefixDBTxn{
+		prefix: prefix,
+		source: source,
+	}
+}
+
+// Get implements Txn.
+func (pt *prefixDBTxn) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	return pt.source.Get(append(cp(pt.prefix), key...))
+}
+
+// Has implements Txn.
+func (pt *prefixDBTxn) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	return pt.source.Has(append(cp(pt.prefix), key...))
+}
+
+// Set implements Txn.
+func (pt *prefixDBTxn) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return pt.source.Set(append(cp(pt.prefix), key...), value)
+}
+
+// Delete implements Txn.
+func (pt *prefixDBTxn) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return pt.source.Delete(append(cp(pt.prefix), key...))
+}
+
+// Iterator implements Txn.
+func (pt *prefixDBTxn) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+
+	pstart, pend := prefixDomain(pt.prefix, start, end)
+	itr, err := pt.source.Iterator(pstart, pend)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPrefixIterator(pt.prefix, start, end, itr)
+}
+
+// ReverseIterator implements Txn.
+func (pt *prefixDBTxn) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+
+	pstart, pend := prefixDomain(pt.prefix, start, end)
+	ritr, err := pt.source.ReverseIterator(pstart, pend)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPrefixIterator(pt.prefix, start, end, ritr)
+}
+
+// Commit implements Txn. Conflicting keys are reported without the prefix.
+func (pt *prefixDBTxn) Commit() error {
+	err := pt.source.Commit()
+	var conflict *ConflictError
+	if errors.As(err, &conflict) && bytes.HasPrefix(conflict.Key, pt.prefix) {
+		return &ConflictError{Key: conflict.Key[len(pt.prefix):]}
+	}
+	return err
+}
+
+// Discard implements Txn.
+func (pt *prefixDBTxn) Discard() error {
+	return pt.source.Discard()
+}
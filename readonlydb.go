@@ -0,0 +1,210 @@
+package db
+
+import "context"
+
+// readOnlyDB wraps a database, rejecting writes with a *ReadOnlyError.
+type readOnlyDB struct {
+	db DB
+}
+
+var (
+	_ ContextDB        = (*readOnlyDB)(nil)
+	_ PrefixIteratorDB = (*readOnlyDB)(nil)
+)
+
+// NewReadOnlyDB wraps a database such that all writes, including via batches and transactions,
+// fail with a *ReadOnlyError. Reads, iterators and snapshots are passed through.
+func NewReadOnlyDB(db DB) DB {
+	return &readOnlyDB{db: db}
+}
+
+// WithContext implements ContextDB, binding the underlying database to the context.
+func (rdb *readOnlyDB) WithContext(ctx context.Context) DB {
+	return NewReadOnlyDB(WithContext(ctx, rdb.db))
+}
+
+// Get implements DB.
+func (rdb *readOnlyDB) Get(key []byte) ([]byte, error) {
+	return rdb.db.Get(key)
+}
+
+// Has implements DB.
+func (rdb *readOnlyDB) Has(key []byte) (bool, error) {
+	return rdb.db.Has(key)
+}
+
+// MultiGet implements DB.
+func (rdb *readOnlyDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return rdb.db.MultiGet(keys)
+}
+
+// Set implements DB.
+func (rdb *readOnlyDB) Set(key, value []byte) error {
+	return &ReadOnlyError{Op: "Set"}
+}
+
+// SetSync implements DB.
+func (rdb *readOnlyDB) SetSync(key, value []byte) error {
+	return &ReadOnlyError{Op: "SetSync"}
+}
+
+// Delete implements DB.
+func (rdb *readOnlyDB) Delete(key []byte) error {
+	return &ReadOnlyError{Op: "Delete"}
+}
+
+// DeleteSync implements DB.
+func (rdb *readOnlyDB) DeleteSync(key []byte) error {
+	return &ReadOnlyError{Op: "DeleteSync"}
+}
+
+// DeleteRange implements DB.
+func (rdb *readOnlyDB) DeleteRange(start, end []byte) error {
+	return &ReadOnlyError{Op: "DeleteRange"}
+}
+
+// Iterator implements DB.
+func (rdb *readOnlyDB) Iterator(start, end []byte) (Iterator, error) {
+	return rdb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (rdb *readOnlyDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return rdb.db.ReverseIterator(start, end)
+}
+
+// IteratorWithOptions implements DB.
+func (rdb *readOnlyDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	return rdb.db.IteratorWithOptions(start, end, opts)
+}
+
+// IteratePrefix implements PrefixIteratorDB.
+func (rdb *readOnlyDB) IteratePrefix(prefix []byte) (Iterator, error) {
+	return IteratePrefix(rdb.db, prefix)
+}
+
+// ReverseIteratePrefix implements PrefixIteratorDB.
+func (rdb *readOnlyDB) ReverseIteratePrefix(prefix []byte) (Iterator, error) {
+	return ReverseIteratePrefix(rdb.db, prefix)
+}
+
+// Close implements DB.
+func (rdb *readOnlyDB) Close() error {
+	return rdb.db.Close()
+}
+
+// NewBatch implements DB. Operations on the batch fail with a *ReadOnlyError.
+func (rdb *readOnlyDB) NewBatch() Batch {
+	return &readOnlyBatch{}
+}
+
+// Print implements DB.
+func (rdb *readOnlyDB) Print() error {
+	return rdb.db.Print()
+}
+
+// Stats implements DB.
+func (rdb *readOnlyDB) Stats() map[string]string {
+	return rdb.db.Stats()
+}
+
+// TypedStats implements DB.
+func (rdb *readOnlyDB) TypedStats() DBStats {
+	return rdb.db.TypedStats()
+}
+
+// Capabilities implements DB.
+func (rdb *readOnlyDB) Capabilities() Capabilities {
+	return rdb.db.Capabilities()
+}
+
+// Snapshot implements DB.
+func (rdb *readOnlyDB) Snapshot() (Snapshot, error) {
+	return rdb.db.Snapshot()
+}
+
+// NewTxn implements DB. The transaction can be read from, but writes fail with a *ReadOnlyError.
+func (rdb *readOnlyDB) NewTxn() (Txn, error) {
+	txn, err := rdb.db.NewTxn()
+	if err != nil {
+		return nil, err
+	}
+	return &readOnlyTxn{Txn: txn}, nil
+}
+
+// Checkpoint implements DB. Checkpoints don't write to the database, so they are allowed.
+func (rdb *readOnlyDB) Checkpoint(dir string) error {
+	return rdb.db.Checkpoint(dir)
+}
+
+// readOnlyBatch is a batch on a read-only database, which rejects all writes.
+type readOnlyBatch struct {
+	closed bool
+}
+
+var _ Batch = (*readOnlyBatch)(nil)
+
+// Set implements Batch.
+func (b *readOnlyBatch) Set(key, value []byte) error {
+	return &ReadOnlyError{Op: "Set"}
+}
+
+// Delete implements Batch.
+func (b *readOnlyBatch) Delete(key []byte) error {
+	return &ReadOnlyError{Op: "Delete"}
+}
+
+// DeleteRange implements Batch.
+func (b *readOnlyBatch) DeleteRange(start, end []byte) error {
+	return &ReadOnlyError{Op: "DeleteRange"}
+}
+
+// GetByteSize implements Batch.
+func (b *readOnlyBatch) GetByteSize() (int, error) {
+	if b.closed {
+		return 0, errBatchClosed
+	}
+	return 0, nil
+}
+
+// Len implements Batch.
+func (b *readOnlyBatch) Len() int {
+	return 0
+}
+
+// Write implements Batch.
+func (b *readOnlyBatch) Write() error {
+	return &ReadOnlyError{Op: "Write"}
+}
+
+// WriteSync implements Batch.
+func (b *readOnlyBatch) WriteSync() error {
+	return &ReadOnlyError{Op: "WriteSync"}
+}
+
+// Close implements Batch.
+func (b *readOnlyBatch) Close() error {
+	b.closed = true
+	return nil
+}
+
+// readOnlyTxn is a transaction on a read-only database, which rejects all writes. Since it can't
+// have any writes, committing it just discards it.
+type readOnlyTxn struct {
+	Txn
+}
+
+// Set implements Txn.
+func (txn *readOnlyTxn) Set(key, value []byte) error {
+	return &ReadOnlyError{Op: "Set"}
+}
+
+// Delete implements Txn.
+func (txn *readOnlyTxn) Delete(key []byte) error {
+	return &ReadOnlyError{Op: "Delete"}
+}
+
+// Commit implements Txn.
+func (txn *readOnlyTxn) Commit() error {
+	return txn.Txn.Discard()
+}
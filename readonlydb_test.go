@@ -0,0 +1,55 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyDB(t *testing.T) {
+	source := NewMemDB()
+	require.NoError(t, source.Set([]byte("a"), []byte{1}))
+	db := NewReadOnlyDB(source)
+
+	value, err := db.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, value)
+	itr, err := IteratePrefix(db, []byte("a"))
+	require.NoError(t, err)
+	require.True(t, itr.Valid())
+	require.NoError(t, itr.Close())
+
+	for op, err := range map[string]error{
+		"Set":         db.Set([]byte("b"), []byte{2}),
+		"SetSync":     db.SetSync([]byte("b"), []byte{2}),
+		"Delete":      db.Delete([]byte("a")),
+		"DeleteSync":  db.DeleteSync([]byte("a")),
+		"DeleteRange": db.DeleteRange(nil, nil),
+	} {
+		var readOnlyErr *ReadOnlyError
+		require.True(t, errors.As(err, &readOnlyErr), op)
+		require.Equal(t, op, readOnlyErr.Op)
+		require.EqualError(t, err, "cannot "+op+": database is read-only")
+	}
+
+	batch := db.NewBatch()
+	require.Equal(t, &ReadOnlyError{Op: "Set"}, batch.Set([]byte("b"), []byte{2}))
+	require.Equal(t, &ReadOnlyError{Op: "Delete"}, batch.Delete([]byte("a")))
+	require.Equal(t, &ReadOnlyError{Op: "Write"}, batch.Write())
+	require.Equal(t, &ReadOnlyError{Op: "WriteSync"}, batch.WriteSync())
+	require.NoError(t, batch.Close())
+
+	txn, err := db.NewTxn()
+	require.NoError(t, err)
+	value, err = txn.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, value)
+	require.Equal(t, &ReadOnlyError{Op: "Set"}, txn.Set([]byte("b"), []byte{2}))
+	require.NoError(t, txn.Commit())
+	require.NoError(t, txn.Discard())
+
+	// The underlying database is unchanged.
+	assertKeyValues(t, source, map[string][]byte{"a": {1}})
+	require.NoError(t, db.Close())
+}
@@ -8,11 +8,15 @@ import (
 	protodb "github.com/tendermint/tm-db/remotedb/proto"
 )
 
-var errBatchClosed = errors.New("batch has been written or closed")
+var (
+	errBatchClosed = errors.New("batch has been written or closed")
+	errKeyEmpty    = errors.New("key cannot be empty")
+)
 
 type batch struct {
-	db  *RemoteDB
-	ops []*protodb.Operation
+	db   *RemoteDB
+	ops  []*protodb.Operation
+	size int // size of the keys and values in ops
 }
 
 var _ db.Batch = (*batch)(nil)
@@ -34,6 +38,7 @@ func (b *batch) Set(key, value []byte) error {
 		Type:   protodb.Operation_SET,
 	}
 	b.ops = append(b.ops, op)
+	b.size += len(key) + len(value)
 	return nil
 }
 
@@ -47,9 +52,57 @@ func (b *batch) Delete(key []byte) error {
 		Type:   protodb.Operation_DELETE,
 	}
 	b.ops = append(b.ops, op)
+	b.size += len(key)
+	return nil
+}
+
+// DeleteRange implements Batch. The gRPC service has no range deletion, so the range is resolved
+// to the keys present in the database and the batch when it is called.
+func (b *batch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	var keys [][]byte
+	for _, op := range b.ops {
+		if op.Type == protodb.Operation_SET && db.IsKeyInDomain(op.Entity.Key, start, end) {
+			keys = append(keys, op.Entity.Key)
+		}
+	}
+	itr, err := b.db.Iterator(start, end)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, itr.Key())
+	}
+	if err := itr.Error(); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := b.Delete(key); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// GetByteSize implements Batch.
+func (b *batch) GetByteSize() (int, error) {
+	if b.ops == nil {
+		return 0, errBatchClosed
+	}
+	return b.size, nil
+}
+
+// Len implements Batch.
+func (b *batch) Len() int {
+	return len(b.ops)
+}
+
 // Write implements Batch.
 func (b *batch) Write() error {
 	if b.ops == nil {
@@ -80,5 +133,6 @@ func (b *batch) WriteSync() error {
 // Close implements Batch.
 func (b *batch) Close() error {
 	b.ops = nil
+	b.size = 0
 	return nil
 }
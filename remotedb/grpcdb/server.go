@@ -2,6 +2,7 @@ package grpcdb
 
 import (
 	"context"
+	"io"
 	"net"
 	"sync"
 	"time"
@@ -105,6 +106,10 @@ func (s *server) GetStream(ds protodb.DB_GetStreamServer) error {
 		ctx := context.Background()
 		for {
 			in, err := ds.Recv()
+			if err == io.EOF {
+				// The client has sent all of its keys.
+				return
+			}
 			if err != nil {
 				responsesChan <- &protodb.Entity{Err: err.Error()}
 				return
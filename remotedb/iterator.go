@@ -1,26 +1,35 @@
 package remotedb
 
 import (
+	"bytes"
+	"context"
+
 	db "github.com/tendermint/tm-db"
 	protodb "github.com/tendermint/tm-db/remotedb/proto"
 )
 
-func makeIterator(dic protodb.DB_IteratorClient) db.Iterator {
-	itr := &iterator{dic: dic}
+func makeIterator(rd *RemoteDB, start, end []byte, dic protodb.DB_IteratorClient,
+	cancel context.CancelFunc) db.Iterator {
+	itr := &iterator{rd: rd, start: start, end: end, dic: dic, cancel: cancel}
 	itr.Next() // We need to call Next to prime the iterator
 	return itr
 }
 
-func makeReverseIterator(dric protodb.DB_ReverseIteratorClient) db.Iterator {
-	rItr := &reverseIterator{dric: dric}
+func makeReverseIterator(rd *RemoteDB, start, end []byte, dric protodb.DB_ReverseIteratorClient,
+	cancel context.CancelFunc) db.Iterator {
+	rItr := &reverseIterator{rd: rd, start: start, end: end, dric: dric, cancel: cancel}
 	rItr.Next() // We need to call Next to prime the iterator
 	return rItr
 }
 
 type reverseIterator struct {
-	dric protodb.DB_ReverseIteratorClient
-	cur  *protodb.Iterator
-	err  error
+	rd         *RemoteDB
+	start, end []byte
+	dric       protodb.DB_ReverseIteratorClient
+	cancel     context.CancelFunc // cancels the stream
+	cur        *protodb.Iterator
+	err        error
+	keysOnly   bool
 }
 
 var _ db.Iterator = (*iterator)(nil)
@@ -32,10 +41,7 @@ func (rItr *reverseIterator) Valid() bool {
 
 // Domain implements Iterator.
 func (rItr *reverseIterator) Domain() (start, end []byte) {
-	if rItr.cur == nil || rItr.cur.Domain == nil {
-		return nil, nil
-	}
-	return rItr.cur.Domain.Start, rItr.cur.Domain.End
+	return rItr.start, rItr.end
 }
 
 // Next implements Iterator.
@@ -47,6 +53,23 @@ func (rItr *reverseIterator) Next() {
 	}
 }
 
+// Seek implements Iterator. The gRPC service can't reposition iterators, so this opens a new
+// iterator stream over the remainder of the domain.
+func (rItr *reverseIterator) Seek(key []byte) {
+	end := append(append([]byte{}, key...), 0) // the largest key <= key is < key+0x00
+	if rItr.end != nil && bytes.Compare(end, rItr.end) > 0 {
+		end = rItr.end
+	}
+	rItr.cancel()
+	rItr.cur = nil
+	var ctx context.Context
+	ctx, rItr.cancel = context.WithCancel(rItr.rd.ctx)
+	rItr.dric, rItr.err = rItr.rd.dc.ReverseIterator(ctx, &protodb.Entity{Start: rItr.start, End: end})
+	if rItr.err == nil {
+		rItr.Next()
+	}
+}
+
 // Key implements Iterator.
 func (rItr *reverseIterator) Key() []byte {
 	rItr.assertIsValid()
@@ -56,6 +79,9 @@ func (rItr *reverseIterator) Key() []byte {
 // Value implements Iterator.
 func (rItr *reverseIterator) Value() []byte {
 	rItr.assertIsValid()
+	if rItr.keysOnly {
+		return nil
+	}
 	return rItr.cur.Value
 }
 
@@ -64,8 +90,9 @@ func (rItr *reverseIterator) Error() error {
 	return rItr.err
 }
 
-// Close implements Iterator.
+// Close implements Iterator. It cancels the stream, which stops the server from sending items.
 func (rItr *reverseIterator) Close() error {
+	rItr.cancel()
 	return nil
 }
 
@@ -80,9 +107,13 @@ func (rItr *reverseIterator) assertIsValid() {
 // needed. It is NOT safe for concurrent usage,
 // matching the behavior of other iterators.
 type iterator struct {
-	dic protodb.DB_IteratorClient
-	cur *protodb.Iterator
-	err error
+	rd         *RemoteDB
+	start, end []byte
+	dic        protodb.DB_IteratorClient
+	cancel     context.CancelFunc // cancels the stream
+	cur        *protodb.Iterator
+	err        error
+	keysOnly   bool
 }
 
 var _ db.Iterator = (*iterator)(nil)
@@ -94,10 +125,7 @@ func (itr *iterator) Valid() bool {
 
 // Domain implements Iterator.
 func (itr *iterator) Domain() (start, end []byte) {
-	if itr.cur == nil || itr.cur.Domain == nil {
-		return nil, nil
-	}
-	return itr.cur.Domain.Start, itr.cur.Domain.End
+	return itr.start, itr.end
 }
 
 // Next implements Iterator.
@@ -109,6 +137,23 @@ func (itr *iterator) Next() {
 	}
 }
 
+// Seek implements Iterator. The gRPC service can't reposition iterators, so this opens a new
+// iterator stream over the remainder of the domain.
+func (itr *iterator) Seek(key []byte) {
+	start := itr.start
+	if bytes.Compare(key, start) > 0 {
+		start = key
+	}
+	itr.cancel()
+	itr.cur = nil
+	var ctx context.Context
+	ctx, itr.cancel = context.WithCancel(itr.rd.ctx)
+	itr.dic, itr.err = itr.rd.dc.Iterator(ctx, &protodb.Entity{Start: start, End: itr.end})
+	if itr.err == nil {
+		itr.Next()
+	}
+}
+
 // Key implements Iterator.
 func (itr *iterator) Key() []byte {
 	itr.assertIsValid()
@@ -118,6 +163,9 @@ func (itr *iterator) Key() []byte {
 // Value implements Iterator.
 func (itr *iterator) Value() []byte {
 	itr.assertIsValid()
+	if itr.keysOnly {
+		return nil
+	}
 	return itr.cur.Value
 }
 
@@ -126,9 +174,10 @@ func (itr *iterator) Error() error {
 	return itr.err
 }
 
-// Close implements Iterator.
+// Close implements Iterator. It cancels the stream, which stops the server from sending items.
 func (itr *iterator) Close() error {
-	return itr.dic.CloseSend()
+	itr.cancel()
+	return nil
 }
 
 func (itr *iterator) assertIsValid() {
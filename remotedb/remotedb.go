@@ -26,6 +26,14 @@ func newRemoteDB(gdc protodb.DBClient, err error) (*RemoteDB, error) {
 	return &RemoteDB{dc: gdc, ctx: context.Background()}, nil
 }
 
+var _ db.ContextDB = (*RemoteDB)(nil)
+
+// WithContext returns a view of the remote database which makes its gRPC calls, including those of
+// its iterators and batches, with the given context. It shares the connection with the original.
+func (rd *RemoteDB) WithContext(ctx context.Context) db.DB {
+	return &RemoteDB{dc: rd.dc, ctx: ctx}
+}
+
 type Init struct {
 	Dir  string
 	Name string
@@ -58,6 +66,20 @@ func (rd *RemoteDB) DeleteSync(key []byte) error {
 	return nil
 }
 
+// DeleteRange deletes the keys in the range one by one in a single batch, since the gRPC service
+// has no range deletion.
+func (rd *RemoteDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	b := newBatch(rd)
+	defer b.Close()
+	if err := b.DeleteRange(start, end); err != nil {
+		return err
+	}
+	return b.Write()
+}
+
 func (rd *RemoteDB) Set(key, value []byte) error {
 	if _, err := rd.dc.Set(rd.ctx, &protodb.Entity{Key: key, Value: value}); err != nil {
 		return fmt.Errorf("remoteDB.Set: %w", err)
@@ -80,6 +102,45 @@ func (rd *RemoteDB) Get(key []byte) ([]byte, error) {
 	return res.Value, nil
 }
 
+// MultiGet fetches all keys over a single getStream call, rather than one round-trip per key.
+func (rd *RemoteDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	// Cancelling the stream on return stops the sender below if we fail early.
+	ctx, cancel := context.WithCancel(rd.ctx)
+	defer cancel()
+	stream, err := rd.dc.GetStream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("remoteDB.MultiGet: %w", err)
+	}
+
+	// Send the keys concurrently with receiving the values, to avoid blocking on flow control.
+	sendErr := make(chan error, 1)
+	go func() {
+		for _, key := range keys {
+			if err := stream.Send(&protodb.Entity{Key: key}); err != nil {
+				sendErr <- err
+				return
+			}
+		}
+		sendErr <- stream.CloseSend()
+	}()
+
+	values := make([][]byte, len(keys))
+	for i := range keys {
+		res, err := stream.Recv()
+		if err != nil {
+			return nil, fmt.Errorf("remoteDB.MultiGet: %w", err)
+		}
+		if res.Err != "" {
+			return nil, fmt.Errorf("remoteDB.MultiGet: %s", res.Err)
+		}
+		values[i] = res.Value
+	}
+	if err := <-sendErr; err != nil {
+		return nil, fmt.Errorf("remoteDB.MultiGet: %w", err)
+	}
+	return values, nil
+}
+
 func (rd *RemoteDB) Has(key []byte) (bool, error) {
 	res, err := rd.dc.Has(rd.ctx, &protodb.Entity{Key: key})
 	if err != nil {
@@ -89,11 +150,15 @@ func (rd *RemoteDB) Has(key []byte) (bool, error) {
 }
 
 func (rd *RemoteDB) ReverseIterator(start, end []byte) (db.Iterator, error) {
-	dic, err := rd.dc.ReverseIterator(rd.ctx, &protodb.Entity{Start: start, End: end})
+	// The stream is cancelled when the iterator is closed or sought, since the server keeps
+	// sending items until the stream's context is done.
+	ctx, cancel := context.WithCancel(rd.ctx)
+	dric, err := rd.dc.ReverseIterator(ctx, &protodb.Entity{Start: start, End: end})
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("RemoteDB.Iterator error: %w", err)
 	}
-	return makeReverseIterator(dic), nil
+	return makeReverseIterator(rd, start, end, dric, cancel), nil
 }
 
 func (rd *RemoteDB) NewBatch() db.Batch {
@@ -114,10 +179,67 @@ func (rd *RemoteDB) Stats() map[string]string {
 	return stats.Data
 }
 
+// TypedStats implements DB. The gRPC service only provides the raw Stats map, so no statistics are
+// reported.
+func (rd *RemoteDB) TypedStats() db.DBStats {
+	return db.DBStats{
+		ApproximateKeys:        -1,
+		DiskSize:               -1,
+		MemtableSize:           -1,
+		CacheHits:              -1,
+		CacheMisses:            -1,
+		CompactionPendingBytes: -1,
+		OpenIterators:          -1,
+	}
+}
+
+// Capabilities implements DB. The gRPC service doesn't report the capabilities of the remote
+// database, so none are reported.
+func (rd *RemoteDB) Capabilities() db.Capabilities {
+	return db.Capabilities{}
+}
+
+// TODO: Implement Snapshot when the gRPC service supports server-side snapshots.
+func (rd *RemoteDB) Snapshot() (db.Snapshot, error) {
+	return nil, errors.New("remoteDB.Snapshot: unimplemented")
+}
+
+// TODO: Implement NewTxn when the gRPC service supports transactions.
+func (rd *RemoteDB) NewTxn() (db.Txn, error) {
+	return nil, errors.New("remoteDB.NewTxn: unimplemented")
+}
+
+// TODO: Implement Checkpoint when the gRPC service supports server-side checkpoints.
+func (rd *RemoteDB) Checkpoint(dir string) error {
+	return errors.New("remoteDB.Checkpoint: unimplemented")
+}
+
 func (rd *RemoteDB) Iterator(start, end []byte) (db.Iterator, error) {
-	dic, err := rd.dc.Iterator(rd.ctx, &protodb.Entity{Start: start, End: end})
+	// The stream is cancelled when the iterator is closed or sought, see ReverseIterator.
+	ctx, cancel := context.WithCancel(rd.ctx)
+	dic, err := rd.dc.Iterator(ctx, &protodb.Entity{Start: start, End: end})
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("RemoteDB.Iterator error: %w", err)
 	}
-	return makeIterator(dic), nil
+	return makeIterator(rd, start, end, dic, cancel), nil
+}
+
+// IteratorWithOptions implements DB. The gRPC service always sends values, so key-only iterators
+// only hide them.
+func (rd *RemoteDB) IteratorWithOptions(start, end []byte, opts db.IteratorOptions) (db.Iterator, error) {
+	if opts.Reverse {
+		itr, err := rd.ReverseIterator(start, end)
+		if err != nil {
+			return nil, err
+		}
+		itr.(*reverseIterator).keysOnly = opts.KeysOnly
+		return itr, nil
+	}
+	itr, err := rd.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	itr.(*iterator).keysOnly = opts.KeysOnly
+	return itr, nil
 }
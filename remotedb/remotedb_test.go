@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	db "github.com/tendermint/tm-db"
 	"github.com/tendermint/tm-db/remotedb"
 	"github.com/tendermint/tm-db/remotedb/grpcdb"
 )
@@ -86,6 +87,33 @@ func TestRemoteDB(t *testing.T) {
 	require.Equal(t, value, []byte("value-2"))
 	itr.Close()
 
+	// Seeking
+	itr, err = client.Iterator(nil, nil)
+	require.NoError(t, err)
+	itr.Seek([]byte("key-11"))
+	require.True(t, itr.Valid())
+	require.Equal(t, []byte("key-2"), itr.Key())
+	itr.Seek([]byte("key-1"))
+	require.True(t, itr.Valid())
+	require.Equal(t, []byte("key-1"), itr.Key())
+	itr.Close()
+
+	itr, err = client.ReverseIterator(nil, []byte("key-2"))
+	require.NoError(t, err)
+	itr.Seek([]byte("key-3"))
+	require.True(t, itr.Valid())
+	require.Equal(t, []byte("key-1"), itr.Key())
+	itr.Seek([]byte("key-0"))
+	require.False(t, itr.Valid())
+	itr.Close()
+
+	// Key-only iteration
+	itr, err = client.IteratorWithOptions(nil, nil, db.IteratorOptions{Reverse: true, KeysOnly: true})
+	require.NoError(t, err)
+	require.Equal(t, []byte("key-2"), itr.Key())
+	require.Nil(t, itr.Value())
+	itr.Close()
+
 	// Deletion
 	err = client.Delete(k1)
 	require.NoError(t, err)
@@ -164,4 +192,15 @@ func TestRemoteDB(t *testing.T) {
 	rv5, err := client.Get(k5)
 	require.NoError(t, err)
 	require.Equal(t, rv5, v5, "expecting k5 to have been stored")
+
+	// MultiGet
+	values, err := client.MultiGet([][]byte{k5, k4, k5})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{v5, nil, v5}, values)
+
+	// Empty keys in range deletions are rejected without calling the server
+	require.EqualError(t, client.DeleteRange([]byte{}, nil), "key cannot be empty")
+	bat = client.NewBatch()
+	require.EqualError(t, bat.DeleteRange(nil, []byte{}), "key cannot be empty")
+	require.NoError(t, bat.Close())
 }
@@ -4,16 +4,19 @@
 package db
 
 import (
+	"bytes"
 	"fmt"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"sync"
 
 	"github.com/cosmos/gorocksdb"
 )
 
 func init() {
-	dbCreator := func(name string, dir string) (DB, error) {
-		return NewRocksDB(name, dir)
+	dbCreator := func(name string, dir string, opts Options) (DB, error) {
+		return newRocksDB(name, dir, opts)
 	}
 	registerDBCreator(RocksDBBackend, dbCreator, false)
 }
@@ -24,46 +27,95 @@ type RocksDB struct {
 	ro     *gorocksdb.ReadOptions
 	wo     *gorocksdb.WriteOptions
 	woSync *gorocksdb.WriteOptions
+	txnMtx sync.Mutex
 }
 
-var _ DB = (*RocksDB)(nil)
+var (
+	_ DB               = (*RocksDB)(nil)
+	_ PrefixIteratorDB = (*RocksDB)(nil)
+)
 
 func NewRocksDB(name string, dir string) (*RocksDB, error) {
+	return newRocksDB(name, dir, nil)
+}
+
+// newRocksDB creates a new RocksDB with options passed to NewDBWithOptions. Supported options are:
+//
+//   - block_cache_size: the capacity of the block cache, in bytes (default 1 GB)
+//   - memtable_memory_budget: the memory budget for memtables, in bytes (default 512 MB)
+//   - max_open_files: the maximum number of open files (default 4096)
+//   - read_only: whether to open an existing database read-only, which can be done while another
+//     process has it open for writing, reading the data as of when it was opened
+func newRocksDB(name string, dir string, o Options) (*RocksDB, error) {
+	blockCacheSize, err := optInt(o, "block_cache_size", 1<<30)
+	if err != nil {
+		return nil, err
+	}
+	memtableMemoryBudget, err := optInt(o, "memtable_memory_budget", 512*1024*1024)
+	if err != nil {
+		return nil, err
+	}
+	maxOpenFiles, err := optInt(o, "max_open_files", 4096)
+	if err != nil {
+		return nil, err
+	}
+	readOnly, err := optBool(o, "read_only", false)
+	if err != nil {
+		return nil, err
+	}
+
 	// default rocksdb option, good enough for most cases, including heavy workloads.
 	// 1GB table cache, 512MB write buffer(may use 50% more on heavy workloads).
 	// compression: snappy as default, need to -lsnappy to enable.
 	bbto := gorocksdb.NewDefaultBlockBasedTableOptions()
-	bbto.SetBlockCache(gorocksdb.NewLRUCache(1 << 30))
+	bbto.SetBlockCache(gorocksdb.NewLRUCache(uint64(blockCacheSize)))
 	bbto.SetFilterPolicy(gorocksdb.NewBloomFilter(10))
 
 	opts := gorocksdb.NewDefaultOptions()
 	opts.SetBlockBasedTableFactory(bbto)
 	// SetMaxOpenFiles to 4096 seems to provide a reliable performance boost
-	opts.SetMaxOpenFiles(4096)
-	opts.SetCreateIfMissing(true)
+	opts.SetMaxOpenFiles(maxOpenFiles)
+	opts.SetCreateIfMissing(!readOnly)
 	opts.IncreaseParallelism(runtime.NumCPU())
 	// 1.5GB maximum memory use for writebuffer.
-	opts.OptimizeLevelStyleCompaction(512 * 1024 * 1024)
+	opts.OptimizeLevelStyleCompaction(uint64(memtableMemoryBudget))
+	if readOnly {
+		return NewReadOnlyRocksDBWithOptions(name, dir, opts)
+	}
 	return NewRocksDBWithOptions(name, dir, opts)
 }
 
 func NewRocksDBWithOptions(name string, dir string, opts *gorocksdb.Options) (*RocksDB, error) {
-	dbPath := filepath.Join(dir, name+".db")
-	db, err := gorocksdb.OpenDb(opts, dbPath)
+	db, err := gorocksdb.OpenDb(opts, filepath.Join(dir, name+".db"))
 	if err != nil {
 		return nil, err
 	}
+	return newRocksDBFromDB(db), nil
+}
+
+// NewReadOnlyRocksDBWithOptions opens an existing RocksDB read-only. This can be done while another
+// process has the database open for writing, but writes made after it is opened are not visible.
+// Writes fail with an error from RocksDB.
+func NewReadOnlyRocksDBWithOptions(name string, dir string, opts *gorocksdb.Options) (*RocksDB, error) {
+	db, err := gorocksdb.OpenDbForReadOnly(opts, filepath.Join(dir, name+".db"), false)
+	if err != nil {
+		return nil, err
+	}
+	return newRocksDBFromDB(db), nil
+}
+
+// newRocksDBFromDB creates a RocksDB for an opened database.
+func newRocksDBFromDB(db *gorocksdb.DB) *RocksDB {
 	ro := gorocksdb.NewDefaultReadOptions()
 	wo := gorocksdb.NewDefaultWriteOptions()
 	woSync := gorocksdb.NewDefaultWriteOptions()
 	woSync.SetSync(true)
-	database := &RocksDB{
+	return &RocksDB{
 		db:     db,
 		ro:     ro,
 		wo:     wo,
 		woSync: woSync,
 	}
-	return database, nil
 }
 
 // Get implements DB.
@@ -87,6 +139,24 @@ func (db *RocksDB) Has(key []byte) (bool, error) {
 	return bytes != nil, nil
 }
 
+// MultiGet implements DB, using RocksDB's native MultiGet.
+func (db *RocksDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+	}
+	res, err := db.db.MultiGet(db.ro, keys...)
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(res))
+	for i, s := range res {
+		values[i] = moveSliceToBytes(s)
+	}
+	return values, nil
+}
+
 // Set implements DB.
 func (db *RocksDB) Set(key []byte, value []byte) error {
 	if len(key) == 0 {
@@ -125,6 +195,47 @@ func (db *RocksDB) DeleteSync(key []byte) error {
 	return db.db.Delete(db.woSync, key)
 }
 
+// DeleteRange implements DB, using RocksDB's native range deletion. With a nil end, the range is
+// resolved to end just past the current last key before it is deleted, so keys written past that
+// key concurrently, i.e. after the range is resolved but before it is deleted, are not deleted.
+func (db *RocksDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	batch := gorocksdb.NewWriteBatch()
+	defer batch.Destroy()
+	db.deleteRange(batch, start, end)
+	return db.db.Write(db.wo, batch)
+}
+
+// deleteRange adds a range deletion to the batch. RocksDB range deletions require an end key, so
+// for an unbounded range the end is set just past the last key in the database or the batch.
+func (db *RocksDB) deleteRange(batch *gorocksdb.WriteBatch, start, end []byte) {
+	if end == nil {
+		var last []byte
+		itr := db.db.NewIterator(db.ro)
+		itr.SeekToLast()
+		if itr.Valid() {
+			last = moveSliceToBytes(itr.Key())
+		}
+		itr.Close()
+		bitr := batch.NewIterator()
+		for bitr.Next() {
+			if key := bitr.Record().Key; bytes.Compare(key, last) > 0 {
+				last = cp(key)
+			}
+		}
+		if last == nil || bytes.Compare(last, start) < 0 {
+			return
+		}
+		end = append(last, 0)
+	}
+	if start == nil {
+		start = []byte{}
+	}
+	batch.DeleteRange(start, end)
+}
+
 func (db *RocksDB) DB() *gorocksdb.DB {
 	return db.db
 }
@@ -153,6 +264,38 @@ func (db *RocksDB) Print() error {
 	return nil
 }
 
+// IteratePrefix implements PrefixIteratorDB.
+func (db *RocksDB) IteratePrefix(prefix []byte) (Iterator, error) {
+	return db.prefixIterator(prefix, false), nil
+}
+
+// ReverseIteratePrefix implements PrefixIteratorDB.
+func (db *RocksDB) ReverseIteratePrefix(prefix []byte) (Iterator, error) {
+	return db.prefixIterator(prefix, true), nil
+}
+
+// prefixIterator creates an iterator over the keys with the given prefix, with read options that
+// bound it to the prefix. Ascending iterators also set prefix_same_as_start, so that a prefix
+// extractor configured via NewRocksDBWithOptions is used to skip files without the prefix. In that
+// case, prefixes must be at least as long as the extracted prefixes.
+func (db *RocksDB) prefixIterator(prefix []byte, isReverse bool) *rocksDBIterator {
+	start, end := prefixRange(prefix)
+	ro := gorocksdb.NewDefaultReadOptions()
+	switch {
+	case isReverse && start != nil:
+		ro.SetIterateLowerBound(start)
+	case !isReverse && start != nil:
+		ro.SetPrefixSameAsStart(true)
+		if end != nil {
+			ro.SetIterateUpperBound(end)
+		}
+	}
+	// The bounds are referenced by RocksDB rather than copied, and are kept alive by the iterator.
+	itr := newRocksDBIterator(db.db.NewIterator(ro), start, end, isReverse)
+	itr.ro = ro
+	return itr
+}
+
 // Stats implements DB.
 func (db *RocksDB) Stats() map[string]string {
 	keys := []string{"rocksdb.stats"}
@@ -163,11 +306,68 @@ func (db *RocksDB) Stats() map[string]string {
 	return stats
 }
 
+// TypedStats implements DB. Cache hits and misses are only collected with statistics enabled in
+// the database options, so they are not reported.
+func (db *RocksDB) TypedStats() DBStats {
+	stats := newDBStats()
+	stats.ApproximateKeys = db.intProperty("rocksdb.estimate-num-keys")
+	stats.DiskSize = db.intProperty("rocksdb.total-sst-files-size")
+	stats.MemtableSize = db.intProperty("rocksdb.cur-size-all-mem-tables")
+	stats.CompactionPendingBytes = db.intProperty("rocksdb.estimate-pending-compaction-bytes")
+	return stats
+}
+
+// intProperty returns the value of an integer property, or -1 if it is unavailable.
+func (db *RocksDB) intProperty(key string) int64 {
+	value, err := strconv.ParseInt(db.db.GetProperty(key), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return value
+}
+
+// Capabilities implements DB.
+func (db *RocksDB) Capabilities() Capabilities {
+	return Capabilities{
+		AtomicBatch:      true,
+		SyncWrites:       true,
+		Snapshot:         true,
+		IteratorSnapshot: true,
+		Compaction:       true,
+		Checkpoint:       true,
+		RangeDelete:      true,
+	}
+}
+
 // NewBatch implements DB.
 func (db *RocksDB) NewBatch() Batch {
 	return newRocksDBBatch(db)
 }
 
+// Snapshot implements DB.
+func (db *RocksDB) Snapshot() (Snapshot, error) {
+	return newRocksDBSnapshot(db), nil
+}
+
+// NewTxn implements DB.
+func (db *RocksDB) NewTxn() (Txn, error) {
+	return newSnapshotTxn(db, newRocksDBSnapshot(db), &db.txnMtx), nil
+}
+
+// Checkpoint implements DB, using a native RocksDB checkpoint. Where possible, its files are
+// hard links to the database's files, so the checkpoint directory should be on the same filesystem.
+func (db *RocksDB) Checkpoint(dir string) error {
+	if err := createCheckpointDir(dir); err != nil {
+		return err
+	}
+	checkpoint, err := db.db.NewCheckpoint()
+	if err != nil {
+		return err
+	}
+	defer checkpoint.Destroy()
+	return checkpoint.CreateCheckpoint(filepath.Join(dir, filepath.Base(db.db.Name())), 0)
+}
+
 // Iterator implements DB.
 func (db *RocksDB) Iterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
@@ -185,3 +385,22 @@ func (db *RocksDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	itr := db.db.NewIterator(db.ro)
 	return newRocksDBIterator(itr, start, end, true), nil
 }
+
+// IteratorWithOptions implements DB.
+func (db *RocksDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	ro := gorocksdb.NewDefaultReadOptions()
+	ro.SetFillCache(!opts.DontFillCache)
+	if opts.ReadaheadSize > 0 {
+		ro.SetReadaheadSize(uint64(opts.ReadaheadSize))
+	}
+	ritr := newRocksDBIterator(db.db.NewIterator(ro), start, end, opts.Reverse)
+	ritr.ro = ro
+	var itr Iterator = ritr
+	if opts.KeysOnly {
+		itr = keysOnlyIterator{itr}
+	}
+	return itr, nil
+}
@@ -8,6 +8,7 @@ import "github.com/cosmos/gorocksdb"
 type rocksDBBatch struct {
 	db    *RocksDB
 	batch *gorocksdb.WriteBatch
+	size  int // size of the keys and values in the batch, since WriteBatch.Data() copies it
 }
 
 var _ Batch = (*rocksDBBatch)(nil)
@@ -31,6 +32,7 @@ func (b *rocksDBBatch) Set(key, value []byte) error {
 		return errBatchClosed
 	}
 	b.batch.Put(key, value)
+	b.size += len(key) + len(value)
 	return nil
 }
 
@@ -43,9 +45,39 @@ func (b *rocksDBBatch) Delete(key []byte) error {
 		return errBatchClosed
 	}
 	b.batch.Delete(key)
+	b.size += len(key)
 	return nil
 }
 
+// DeleteRange implements Batch, using RocksDB's native range deletion.
+func (b *rocksDBBatch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if b.batch == nil {
+		return errBatchClosed
+	}
+	b.db.deleteRange(b.batch, start, end)
+	b.size += len(start) + len(end)
+	return nil
+}
+
+// GetByteSize implements Batch.
+func (b *rocksDBBatch) GetByteSize() (int, error) {
+	if b.batch == nil {
+		return 0, errBatchClosed
+	}
+	return b.size, nil
+}
+
+// Len implements Batch.
+func (b *rocksDBBatch) Len() int {
+	if b.batch == nil {
+		return 0
+	}
+	return b.batch.Count()
+}
+
 // Write implements Batch.
 func (b *rocksDBBatch) Write() error {
 	if b.batch == nil {
@@ -78,6 +110,7 @@ func (b *rocksDBBatch) Close() error {
 	if b.batch != nil {
 		b.batch.Destroy()
 		b.batch = nil
+		b.size = 0
 	}
 	return nil
 }
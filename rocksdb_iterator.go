@@ -11,6 +11,7 @@ import (
 
 type rocksDBIterator struct {
 	source     *gorocksdb.Iterator
+	ro         *gorocksdb.ReadOptions // read options owned by the iterator, if any
 	start, end []byte
 	isReverse  bool
 	isInvalid  bool
@@ -19,34 +20,47 @@ type rocksDBIterator struct {
 var _ Iterator = (*rocksDBIterator)(nil)
 
 func newRocksDBIterator(source *gorocksdb.Iterator, start, end []byte, isReverse bool) *rocksDBIterator {
-	if isReverse {
+	itr := &rocksDBIterator{
+		source:    source,
+		start:     start,
+		end:       end,
+		isReverse: isReverse,
+		isInvalid: false,
+	}
+	itr.position(start, end)
+	return itr
+}
+
+// position positions the source iterator at the first item of the range [start, end) in
+// iteration order.
+func (itr *rocksDBIterator) position(start, end []byte) {
+	if itr.isReverse {
 		if end == nil {
-			source.SeekToLast()
+			itr.source.SeekToLast()
 		} else {
-			source.Seek(end)
-			if source.Valid() {
-				eoakey := moveSliceToBytes(source.Key()) // end or after key
+			itr.source.Seek(end)
+			if itr.source.Valid() {
+				eoakey := moveSliceToBytes(itr.source.Key()) // end or after key
 				if bytes.Compare(end, eoakey) <= 0 {
-					source.Prev()
+					itr.source.Prev()
 				}
 			} else {
-				source.SeekToLast()
+				itr.source.SeekToLast()
 			}
 		}
 	} else {
 		if start == nil {
-			source.SeekToFirst()
+			itr.source.SeekToFirst()
 		} else {
-			source.Seek(start)
+			itr.source.Seek(start)
 		}
 	}
-	return &rocksDBIterator{
-		source:    source,
-		start:     start,
-		end:       end,
-		isReverse: isReverse,
-		isInvalid: false,
-	}
+}
+
+// Seek implements Iterator.
+func (itr *rocksDBIterator) Seek(key []byte) {
+	itr.isInvalid = false
+	itr.position(seekDomain(itr.start, itr.end, key, itr.isReverse))
 }
 
 // Domain implements Iterator.
@@ -123,6 +137,10 @@ func (itr *rocksDBIterator) Error() error {
 // Close implements Iterator.
 func (itr *rocksDBIterator) Close() error {
 	itr.source.Close()
+	if itr.ro != nil {
+		itr.ro.Destroy()
+		itr.ro = nil
+	}
 	return nil
 }
 
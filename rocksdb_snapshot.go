@@ -0,0 +1,72 @@
+//go:build rocksdb
+// +build rocksdb
+
+package db
+
+import "github.com/cosmos/gorocksdb"
+
+// rocksDBSnapshot is a RocksDB snapshot, read through its own read options.
+type rocksDBSnapshot struct {
+	db       *RocksDB
+	snapshot *gorocksdb.Snapshot
+	ro       *gorocksdb.ReadOptions
+}
+
+var _ Snapshot = (*rocksDBSnapshot)(nil)
+
+func newRocksDBSnapshot(db *RocksDB) *rocksDBSnapshot {
+	snapshot := db.db.NewSnapshot()
+	ro := gorocksdb.NewDefaultReadOptions()
+	ro.SetSnapshot(snapshot)
+	return &rocksDBSnapshot{
+		db:       db,
+		snapshot: snapshot,
+		ro:       ro,
+	}
+}
+
+// Get implements Snapshot.
+func (s *rocksDBSnapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	res, err := s.db.db.Get(s.ro, key)
+	if err != nil {
+		return nil, err
+	}
+	return moveSliceToBytes(res), nil
+}
+
+// Has implements Snapshot.
+func (s *rocksDBSnapshot) Has(key []byte) (bool, error) {
+	bytes, err := s.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return bytes != nil, nil
+}
+
+// Iterator implements Snapshot.
+func (s *rocksDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	itr := s.db.db.NewIterator(s.ro)
+	return newRocksDBIterator(itr, start, end, false), nil
+}
+
+// ReverseIterator implements Snapshot.
+func (s *rocksDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	itr := s.db.db.NewIterator(s.ro)
+	return newRocksDBIterator(itr, start, end, true), nil
+}
+
+// Close implements Snapshot.
+func (s *rocksDBSnapshot) Close() error {
+	s.ro.Destroy()
+	s.db.db.ReleaseSnapshot(s.snapshot)
+	return nil
+}
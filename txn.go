@@ -0,0 +1,177 @@
+package db
+
+import (
+	"bytes"
+	"sync"
+)
+
+// snapshotTxn is a generic optimistic transaction, for backends without native transactions. Reads
+// are served from a snapshot of the database, with the transaction's pending writes buffered in a
+// MemDB on top of it. The values observed by Get and Has are recorded, and on commit they are
+// compared with the current values in the database while holding a commit mutex shared by all
+// transactions on the database. If they still match, the writes are applied in a single batch.
+//
+// Writes made directly to the database rather than through transactions are not serialized by
+// the commit mutex, and may therefore race with a commit's conflict checks.
+type snapshotTxn struct {
+	db        DB
+	commitMtx *sync.Mutex
+	source    Snapshot
+	writes    *MemDB            // pending writes, with nil values for deletions
+	reads     map[string][]byte // values observed by reads, with nil for missing keys
+}
+
+var _ Txn = (*snapshotTxn)(nil)
+
+// newSnapshotTxn creates a new transaction on the given database, reading from the given snapshot.
+// The commit mutex must be shared by all transactions on the database.
+func newSnapshotTxn(db DB, source Snapshot, commitMtx *sync.Mutex) *snapshotTxn {
+	return &snapshotTxn{
+		db:        db,
+		commitMtx: commitMtx,
+		source:    source,
+		writes:    NewMemDB(),
+		reads:     make(map[string][]byte),
+	}
+}
+
+// Get implements Txn.
+func (txn *snapshotTxn) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	if txn.writes == nil {
+		return nil, errTxnClosed
+	}
+	if i := txn.writes.btree.Get(newKey(key)); i != nil {
+		return i.(item).value, nil
+	}
+	if value, ok := txn.reads[string(key)]; ok {
+		return value, nil
+	}
+	value, err := txn.source.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	txn.reads[string(key)] = value
+	return value, nil
+}
+
+// Has implements Txn.
+func (txn *snapshotTxn) Has(key []byte) (bool, error) {
+	value, err := txn.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return value != nil, nil
+}
+
+// Set implements Txn.
+func (txn *snapshotTxn) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if txn.writes == nil {
+		return errTxnClosed
+	}
+	txn.writes.set(key, value)
+	return nil
+}
+
+// Delete implements Txn.
+func (txn *snapshotTxn) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if txn.writes == nil {
+		return errTxnClosed
+	}
+	txn.writes.set(key, nil)
+	return nil
+}
+
+// Iterator implements Txn.
+func (txn *snapshotTxn) Iterator(start, end []byte) (Iterator, error) {
+	return txn.newIterator(start, end, false)
+}
+
+// ReverseIterator implements Txn.
+func (txn *snapshotTxn) ReverseIterator(start, end []byte) (Iterator, error) {
+	return txn.newIterator(start, end, true)
+}
+
+func (txn *snapshotTxn) newIterator(start, end []byte, isReverse bool) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	if txn.writes == nil {
+		return nil, errTxnClosed
+	}
+	var (
+		parent Iterator
+		err    error
+	)
+	if isReverse {
+		parent, err = txn.source.ReverseIterator(start, end)
+	} else {
+		parent, err = txn.source.Iterator(start, end)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newMergeIterator(parent, newMemDBIterator(txn.writes, start, end, isReverse), isReverse), nil
+}
+
+// Commit implements Txn.
+func (txn *snapshotTxn) Commit() error {
+	if txn.writes == nil {
+		return errTxnClosed
+	}
+	defer txn.Discard()
+	if txn.writes.btree.Len() == 0 {
+		return nil
+	}
+
+	txn.commitMtx.Lock()
+	defer txn.commitMtx.Unlock()
+
+	for key, read := range txn.reads {
+		value, err := txn.db.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+		if (value == nil) != (read == nil) || !bytes.Equal(value, read) {
+			return &ConflictError{Key: []byte(key)}
+		}
+	}
+
+	batch := txn.db.NewBatch()
+	defer batch.Close()
+	itr := newMemDBIteratorMtxChoice(txn.writes, nil, nil, false, false)
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		var err error
+		if value := itr.Value(); value == nil {
+			err = batch.Delete(itr.Key())
+		} else {
+			err = batch.Set(itr.Key(), value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// Discard implements Txn.
+func (txn *snapshotTxn) Discard() error {
+	if txn.writes == nil {
+		return nil
+	}
+	txn.writes = nil
+	txn.reads = nil
+	return txn.source.Close()
+}
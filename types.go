@@ -1,6 +1,10 @@
 package db
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
 
 var (
 	// errBatchClosed is returned when a closed or written batch is used.
@@ -11,8 +15,38 @@ var (
 
 	// errValueNil is returned when attempting to set a nil value.
 	errValueNil = errors.New("value cannot be nil")
+
+	// errTxnClosed is returned when a committed or discarded transaction is used.
+	errTxnClosed = errors.New("transaction has been committed or discarded")
 )
 
+// ConflictError is returned by Txn.Commit when a key read by the transaction was modified after it
+// was read. The transaction has no effect, and may be retried.
+type ConflictError struct {
+	// Key is the conflicting key, or nil if the backend does not report it.
+	Key []byte
+}
+
+// Error implements error.
+func (e *ConflictError) Error() string {
+	if e.Key == nil {
+		return "transaction conflict"
+	}
+	return fmt.Sprintf("transaction conflict on key %X", e.Key)
+}
+
+// ReadOnlyError is returned when attempting to write to a read-only database, as opened with the
+// read_only option or wrapped with NewReadOnlyDB.
+type ReadOnlyError struct {
+	// Op is the attempted operation, e.g. "Set".
+	Op string
+}
+
+// Error implements error.
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("cannot %s: database is read-only", e.Op)
+}
+
 // DB is the main interface for all database backends. DBs are concurrency-safe. Callers must call
 // Close on the database when done.
 //
@@ -27,6 +61,12 @@ type DB interface {
 	// CONTRACT: key, value readonly []byte
 	Has(key []byte) (bool, error)
 
+	// MultiGet fetches the values of the given keys, in the same order, with nil for keys that
+	// do not exist. It is usually faster than calling Get for each key, and most backends read all
+	// of the keys from a consistent view of the database.
+	// CONTRACT: keys, values readonly []byte
+	MultiGet(keys [][]byte) ([][]byte, error)
+
 	// Set sets the value for the given key, replacing it if it already exists.
 	// CONTRACT: key, value readonly []byte
 	Set([]byte, []byte) error
@@ -41,22 +81,38 @@ type DB interface {
 	// DeleteSync deletes the key, and flushes the delete to storage before returning.
 	DeleteSync([]byte) error
 
+	// DeleteRange deletes all keys in the domain [start, end), with the same semantics as
+	// Iterator: a nil start or end is unbounded, and empty keys are invalid. Backends with native
+	// range deletion apply it atomically, others delete the keys one by one in a single batch. As
+	// native range deletions may require an end key, a nil end may be resolved to just past the
+	// current last key, so keys written past it concurrently may not be deleted; see the backend
+	// documentation.
+	// CONTRACT: start, end readonly []byte
+	DeleteRange(start, end []byte) error
+
 	// Iterator returns an iterator over a domain of keys, in ascending order. The caller must call
 	// Close when done. End is exclusive, and start must be less than end. A nil start iterates
 	// from the first key, and a nil end iterates to the last key (inclusive). Empty keys are not
-	// valid.
-	// CONTRACT: No writes may happen within a domain while an iterator exists over it.
+	// valid. If Capabilities.IteratorSnapshot is true, the iterator operates over an implicit
+	// snapshot of the database, so writes, including to keys within its domain, may be made while
+	// it is open. Otherwise, the iterator may see such writes, so the contract of earlier versions
+	// still applies to the part of the domain it hasn't reached yet: no writes may happen there
+	// while the iterator is open. Keys it has already iterated over may be written.
 	// CONTRACT: start, end readonly []byte
 	Iterator(start, end []byte) (Iterator, error)
 
 	// ReverseIterator returns an iterator over a domain of keys, in descending order. The caller
 	// must call Close when done. End is exclusive, and start must be less than end. A nil end
 	// iterates from the last key (inclusive), and a nil start iterates to the first key (inclusive).
-	// Empty keys are not valid.
-	// CONTRACT: No writes may happen within a domain while an iterator exists over it.
+	// Empty keys are not valid. As with Iterator, writes may be made while the iterator is open.
 	// CONTRACT: start, end readonly []byte
 	ReverseIterator(start, end []byte) (Iterator, error)
 
+	// IteratorWithOptions returns an iterator over a domain of keys, like Iterator or
+	// ReverseIterator, as configured by opts.
+	// CONTRACT: start, end readonly []byte
+	IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error)
+
 	// Close closes the database connection.
 	Close() error
 
@@ -68,10 +124,146 @@ type DB interface {
 
 	// Stats returns a map of property values for all keys and the size of the cache.
 	Stats() map[string]string
+
+	// TypedStats returns statistics about the database. Unlike Stats, the statistics are the same
+	// for all backends, although not all backends report all of them.
+	TypedStats() DBStats
+
+	// Snapshot returns a read-only, point-in-time view of the database, which is not affected by
+	// any writes made after it was created. The caller must call Snapshot.Close when done.
+	Snapshot() (Snapshot, error)
+
+	// NewTxn creates a read-write transaction. The caller must call Txn.Discard.
+	NewTxn() (Txn, error)
+
+	// Checkpoint writes a consistent copy of the database to the directory dir, which must not
+	// exist yet, while the database remains open for reads and writes. The copy can be opened with
+	// NewDB using the same name and backend, and dir as the directory.
+	Checkpoint(dir string) error
+
+	// Capabilities reports the features supported by the database.
+	Capabilities() Capabilities
+}
+
+// Capabilities describes the features supported by a database, so that callers can choose
+// strategies that suit the backend, or fail early if it lacks a feature they rely on.
+type Capabilities struct {
+	// AtomicBatch is true if batches are written atomically. Otherwise, a failed or interrupted
+	// batch write may leave some of its writes applied.
+	AtomicBatch bool
+
+	// SyncWrites is true if sync writes are persisted to disk when they return.
+	SyncWrites bool
+
+	// Snapshot is true if DB.Snapshot is supported.
+	Snapshot bool
+
+	// IteratorSnapshot is true if iterators operate over an implicit snapshot of the database, so
+	// that writes made while iterating are not visible to them.
+	IteratorSnapshot bool
+
+	// Compaction is true if the backend reclaims space used by deleted and overwritten data by
+	// compacting its storage.
+	Compaction bool
+
+	// Checkpoint is true if the backend can natively make consistent on-disk copies of an open
+	// database.
+	Checkpoint bool
+
+	// RangeDelete is true if DeleteRange is native, rather than deleting the keys in the range one
+	// by one.
+	RangeDelete bool
+}
+
+// DBStats are database statistics, as returned by DB.TypedStats. Statistics that the backend
+// doesn't report are -1. Sizes are in bytes.
+type DBStats struct {
+	// ApproximateKeys is an estimate of the number of keys in the database.
+	ApproximateKeys int64
+
+	// DiskSize is the size of the database on disk.
+	DiskSize int64
+
+	// MemtableSize is the size of the in-memory write buffers that have yet to be written to disk.
+	MemtableSize int64
+
+	// CacheHits and CacheMisses are the number of block cache hits and misses.
+	CacheHits   int64
+	CacheMisses int64
+
+	// CompactionPendingBytes is an estimate of the number of bytes that compaction needs to rewrite.
+	CompactionPendingBytes int64
+
+	// OpenIterators is the number of iterators that have not yet been closed.
+	OpenIterators int64
+}
+
+// ContextDB is implemented by databases which can bind their operations to a context, to honor
+// its cancellation and deadline, e.g. by passing it on to remote calls. Use the WithContext
+// function to bind any DB to a context.
+type ContextDB interface {
+	DB
+
+	// WithContext returns a view of the database whose operations are bound to the given context.
+	// Closing the view closes the underlying database.
+	WithContext(ctx context.Context) DB
+}
+
+// IteratorOptions configures iterators created via DB.IteratorWithOptions. The zero value gives
+// the same iterators as DB.Iterator. Options are hints, which backends without a corresponding
+// native option ignore.
+type IteratorOptions struct {
+	// Reverse iterates in descending order, as with DB.ReverseIterator.
+	Reverse bool
+
+	// KeysOnly iterates over keys without reading values, which backends storing values
+	// separately from keys can avoid loading. Value returns nil for key-only iterators.
+	KeysOnly bool
+
+	// DontFillCache avoids adding the blocks read by the iterator to the block cache, so that large
+	// one-off scans don't evict data used by other reads. Used by goleveldb, cleveldb and rocksdb.
+	DontFillCache bool
+
+	// ReadaheadSize is the number of bytes to read ahead from disk, for faster sequential reads.
+	// Used by rocksdb. If 0, the backend default is used.
+	ReadaheadSize int
+
+	// PrefetchSize is the number of items to read ahead of the iterator's position. Used by boltdb
+	// and badgerdb. If 0, the backend default is used.
+	PrefetchSize int
+}
+
+// Snapshot is a read-only, point-in-time view of a database. Reads from a snapshot are isolated
+// from any writes made to the database after the snapshot was taken. Snapshots are
+// concurrency-safe. Callers must close all iterators obtained from a snapshot before calling Close
+// on the snapshot itself, which releases any resources held by the backend.
+//
+// As with DB, keys and values should be considered read-only, and must be copied before they are
+// modified.
+type Snapshot interface {
+	// Get fetches the value of the given key, or nil if it does not exist.
+	// CONTRACT: key, value readonly []byte
+	Get([]byte) ([]byte, error)
+
+	// Has checks if a key exists.
+	// CONTRACT: key, value readonly []byte
+	Has(key []byte) (bool, error)
+
+	// Iterator returns an iterator over a domain of keys, in ascending order. See DB.Iterator.
+	// CONTRACT: start, end readonly []byte
+	Iterator(start, end []byte) (Iterator, error)
+
+	// ReverseIterator returns an iterator over a domain of keys, in descending order. See
+	// DB.ReverseIterator.
+	// CONTRACT: start, end readonly []byte
+	ReverseIterator(start, end []byte) (Iterator, error)
+
+	// Close releases the snapshot. Other methods must not be called afterwards.
+	Close() error
 }
 
 // Batch represents a group of writes. They may or may not be written atomically depending on the
-// backend. Callers must call Close on the batch when done.
+// backend, as reported by DB.Capabilities. Callers must call Close on the batch when done.
 //
 // As with DB, given keys and values should be considered read-only, and must not be modified after
 // passing them to the batch.
@@ -84,6 +276,22 @@ type Batch interface {
 	// CONTRACT: key readonly []byte
 	Delete(key []byte) error
 
+	// DeleteRange deletes all keys in the domain [start, end), including keys set earlier in the
+	// batch, with the same semantics as DB.DeleteRange. Backends without native range deletion
+	// resolve the range to the keys present when DeleteRange is called, or when the batch is
+	// written, so keys written directly to the database in between may or may not be deleted.
+	// CONTRACT: start, end readonly []byte
+	DeleteRange(start, end []byte) error
+
+	// GetByteSize returns the approximate size of the batch in bytes, i.e. the size of its keys and
+	// values plus any encoding overhead reported by the backend.
+	GetByteSize() (int, error)
+
+	// Len returns the number of operations in the batch, or 0 once it has been written or closed.
+	// Range deletions count as one operation on backends with native range deletion, and as one
+	// operation per deleted key otherwise.
+	Len() int
+
 	// Write writes the batch, possibly without flushing to disk. Only Close() can be called after,
 	// other methods will error.
 	Write() error
@@ -96,9 +304,103 @@ type Batch interface {
 	Close() error
 }
 
+// IndexedBatch is a Batch that can also read its pending writes, layered on top of the current
+// contents of the database. It is created with NewIndexedBatch. Unlike Txn, reads are not isolated
+// from other writes to the database, and there is no conflict detection.
+type IndexedBatch interface {
+	Batch
+
+	// Get fetches the value of the given key, taking pending writes into account, or nil if it
+	// does not exist.
+	// CONTRACT: key readonly []byte
+	Get([]byte) ([]byte, error)
+
+	// Has checks if a key exists, taking pending writes into account.
+	// CONTRACT: key readonly []byte
+	Has(key []byte) (bool, error)
+
+	// Iterator returns an iterator over a domain of keys, in ascending order, with the pending
+	// writes merged over the database's contents. Writes made to the batch after the iterator is
+	// created are not visible to it. See DB.Iterator.
+	// CONTRACT: start, end readonly []byte
+	Iterator(start, end []byte) (Iterator, error)
+
+	// ReverseIterator returns an iterator over a domain of keys, in descending order, with the
+	// pending writes merged over the database's contents. See Iterator and DB.ReverseIterator.
+	// CONTRACT: start, end readonly []byte
+	ReverseIterator(start, end []byte) (Iterator, error)
+}
+
+// Txn is a read-write transaction with optimistic concurrency control. Reads observe the state of
+// the database when the transaction was created, along with the transaction's own writes. Writes
+// are buffered until Commit, which applies them atomically, unless a key read via Get or Has has
+// been modified since, in which case it fails with a *ConflictError. Keys seen by iterators are not
+// necessarily checked for conflicts. Callers must call Discard on the transaction when done.
+//
+// Backends that can't keep a snapshot open while writing (as reported by
+// Capabilities.IteratorSnapshot being false, e.g. BoltDB) read from the live database instead. On
+// these, a value is only fixed once the transaction has read it via Get or Has, and iterators see
+// the database as of when they are created, so reads are not repeatable across keys.
+//
+// Transactions are not concurrency-safe. As with DB, given keys and values should be considered
+// read-only, and must not be modified after passing them to the transaction.
+type Txn interface {
+	// Get fetches the value of the given key, or nil if it does not exist.
+	// CONTRACT: key, value readonly []byte
+	Get([]byte) ([]byte, error)
+
+	// Has checks if a key exists.
+	// CONTRACT: key, value readonly []byte
+	Has(key []byte) (bool, error)
+
+	// Set sets the value for the given key, replacing it if it already exists.
+	// CONTRACT: key, value readonly []byte
+	Set(key, value []byte) error
+
+	// Delete deletes the key, or does nothing if the key does not exist.
+	// CONTRACT: key readonly []byte
+	Delete(key []byte) error
+
+	// Iterator returns an iterator over a domain of keys, in ascending order. See DB.Iterator.
+	// CONTRACT: start, end readonly []byte
+	Iterator(start, end []byte) (Iterator, error)
+
+	// ReverseIterator returns an iterator over a domain of keys, in descending order. See
+	// DB.ReverseIterator.
+	// CONTRACT: start, end readonly []byte
+	ReverseIterator(start, end []byte) (Iterator, error)
+
+	// Commit atomically writes the transaction's writes to the database, or returns a
+	// *ConflictError without writing anything. Only Discard() can be called after, other methods
+	// will error.
+	Commit() error
+
+	// Discard discards the transaction. It is idempotent, but calls to other methods afterwards
+	// will error.
+	Discard() error
+}
+
+// PrefixIteratorDB is implemented by databases with native iteration over keys with a given prefix,
+// which IteratePrefix and ReverseIteratePrefix use instead of a range iterator.
+type PrefixIteratorDB interface {
+	DB
+
+	// IteratePrefix returns an iterator over the domain of keys with the given prefix, i.e.
+	// [prefix, PrefixEndBytes(prefix)), in ascending order. An empty prefix iterates over all keys.
+	// CONTRACT: prefix readonly []byte
+	IteratePrefix(prefix []byte) (Iterator, error)
+
+	// ReverseIteratePrefix is like IteratePrefix, but iterates in descending order.
+	// CONTRACT: prefix readonly []byte
+	ReverseIteratePrefix(prefix []byte) (Iterator, error)
+}
+
 // Iterator represents an iterator over a domain of keys. Callers must call Close when done.
-// No writes can happen to a domain while there exists an iterator over it, some backends may take
-// out database locks to ensure this will not happen.
+// Callers may write to the database while iterating, e.g. to delete or rewrite the iterated keys.
+// Iterators obtained from a DB whose Capabilities.IteratorSnapshot is true operate over an implicit
+// snapshot taken when they were created, and thus don't see such writes. Otherwise, writes ahead
+// of the iterator may be visible to it, so callers must not write to the part of the domain that
+// hasn't been iterated over yet, as documented for DB.Iterator.
 //
 // Callers must make sure the iterator is valid before calling any methods on it, otherwise
 // these methods will panic. This is in part caused by most backend databases using this convention.
@@ -124,13 +426,20 @@ type Iterator interface {
 	Domain() (start []byte, end []byte)
 
 	// Valid returns whether the current iterator is valid. Once invalid, the Iterator remains
-	// invalid forever.
+	// invalid until repositioned with Seek.
 	Valid() bool
 
 	// Next moves the iterator to the next key in the database, as defined by order of iteration.
 	// If Valid returns false, this method will panic.
 	Next()
 
+	// Seek moves the iterator to the first key >= key for ascending iterators, or the last
+	// key <= key for descending iterators, without leaving the iterator's domain. If there is no
+	// such key in the domain, the iterator becomes invalid. Seek can be called on an invalid
+	// iterator, but not after Close.
+	// CONTRACT: key readonly []byte
+	Seek(key []byte)
+
 	// Key returns the key at the current position. Panics if the iterator is invalid.
 	// CONTRACT: key readonly []byte
 	Key() (key []byte)
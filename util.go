@@ -11,27 +11,42 @@ func cp(bz []byte) (ret []byte) {
 	return ret
 }
 
-// Returns a slice of the same length (big endian)
-// except incremented by one.
-// Returns nil on overflow (e.g. if bz bytes are all 0xFF)
-// CONTRACT: len(bz) > 0
-func cpIncr(bz []byte) (ret []byte) {
-	if len(bz) == 0 {
-		panic("cpIncr expects non-zero bz length")
-	}
-	ret = cp(bz)
-	for i := len(bz) - 1; i >= 0; i-- {
-		if ret[i] < byte(0xFF) {
-			ret[i]++
-			return
-		}
-		ret[i] = byte(0x00)
-		if i == 0 {
-			// Overflow
-			return nil
-		}
+// PrefixEndBytes returns the end key (exclusive) of the domain of keys with the given prefix. It
+// returns nil if the domain is unbounded, i.e. if the prefix is empty or only contains 0xFF bytes.
+// CONTRACT: prefix readonly []byte
+func PrefixEndBytes(prefix []byte) []byte {
+	// Trailing 0xFF bytes can't be incremented without carrying into a longer key, so they are
+	// stripped, e.g. the domain of {0x01, 0xff} ends at {0x02}.
+	end := cp(prefix)
+	for len(end) > 0 && end[len(end)-1] == 0xff {
+		end = end[:len(end)-1]
+	}
+	if len(end) == 0 {
+		return nil
+	}
+	end[len(end)-1]++
+	return end
+}
+
+// newDBStats returns DBStats where all statistics are unreported.
+func newDBStats() DBStats {
+	return DBStats{
+		ApproximateKeys:        -1,
+		DiskSize:               -1,
+		MemtableSize:           -1,
+		CacheHits:              -1,
+		CacheMisses:            -1,
+		CompactionPendingBytes: -1,
+		OpenIterators:          -1,
+	}
+}
+
+// prefixRange returns the domain of keys with the given prefix.
+func prefixRange(prefix []byte) (start, end []byte) {
+	if len(prefix) == 0 {
+		return nil, nil
 	}
-	return nil
+	return cp(prefix), PrefixEndBytes(prefix)
 }
 
 // See DB interface documentation for more information.
@@ -45,6 +60,39 @@ func IsKeyInDomain(key, start, end []byte) bool {
 	return true
 }
 
+// seekDomain returns the range that remains to be iterated after seeking an iterator over the
+// domain [start, end) to the given key: the range of keys at or after it for ascending iterators,
+// and at or before it for descending iterators. The range may be empty, with start >= end.
+func seekDomain(start, end, key []byte, reverse bool) ([]byte, []byte) {
+	if reverse {
+		// the largest key <= key is the largest key < key+0x00
+		next := append(cp(key), 0)
+		if end == nil || bytes.Compare(next, end) < 0 {
+			end = next
+		}
+		return start, end
+	}
+	if bytes.Compare(key, start) > 0 {
+		start = key
+	}
+	return start, end
+}
+
+// snapshotMultiGet implements DB.MultiGet by reading the keys from a snapshot, which is closed
+// afterwards.
+func snapshotMultiGet(snapshot Snapshot, keys [][]byte) ([][]byte, error) {
+	defer snapshot.Close()
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, err := snapshot.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
 func FileExists(filePath string) bool {
 	_, err := os.Stat(filePath)
 	return !os.IsNotExist(err)
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"testing"
@@ -25,11 +26,6 @@ func TestPrefixIteratorNoMatchNil(t *testing.T) {
 // Empty iterator for db populated after iterator created.
 func TestPrefixIteratorNoMatch1(t *testing.T) {
 	for backend := range backends {
-		if backend == BoltDBBackend {
-			t.Log("bolt does not support concurrent writes while iterating")
-			continue
-		}
-
 		t.Run(fmt.Sprintf("Prefix w/ backend %s", backend), func(t *testing.T) {
 			db, dir := newTempDB(t, backend)
 			defer os.RemoveAll(dir)
@@ -118,3 +114,112 @@ func TestPrefixIteratorMatches1N(t *testing.T) {
 		})
 	}
 }
+
+// Prefix iterators over prefixes ending in 0xFF don't include the keys following the domain.
+func TestPrefixIteratorTrailingFF(t *testing.T) {
+	for backend := range backends {
+		t.Run(fmt.Sprintf("Prefix w/ backend %s", backend), func(t *testing.T) {
+			db, dir := newTempDB(t, backend)
+			defer os.RemoveAll(dir)
+
+			for _, key := range [][]byte{{0x01, 0xfe}, {0x01, 0xff}, {0x01, 0xff, 0x01}, {0x02}, {0x02, 0x00}} {
+				require.NoError(t, db.Set(key, []byte{1}))
+			}
+
+			itr, err := IteratePrefix(db, []byte{0x01, 0xff})
+			require.NoError(t, err)
+			checkValid(t, itr, true)
+			checkItem(t, itr, []byte{0x01, 0xff}, []byte{1})
+			checkNext(t, itr, true)
+			checkItem(t, itr, []byte{0x01, 0xff, 0x01}, []byte{1})
+			checkNext(t, itr, false)
+			require.NoError(t, itr.Close())
+
+			itr, err = ReverseIteratePrefix(db, []byte{0x01, 0xff})
+			require.NoError(t, err)
+			checkValid(t, itr, true)
+			checkItem(t, itr, []byte{0x01, 0xff, 0x01}, []byte{1})
+			checkNext(t, itr, true)
+			checkItem(t, itr, []byte{0x01, 0xff}, []byte{1})
+			checkNext(t, itr, false)
+			require.NoError(t, itr.Close())
+		})
+	}
+}
+
+// Prefix iterators in both directions, including prefixes without an end key.
+func TestPrefixIteratorReverse(t *testing.T) {
+	for backend := range backends {
+		t.Run(fmt.Sprintf("Prefix w/ backend %s", backend), func(t *testing.T) {
+			db, dir := newTempDB(t, backend)
+			defer os.RemoveAll(dir)
+
+			for _, key := range [][]byte{
+				bz("a"), bz("a/1"), bz("a/3"), bz("a0"), bz("b/1"),
+				{0xff}, {0xff, 0x01}, {0xff, 0xff}, {0xff, 0xff, 0x00},
+			} {
+				require.NoError(t, db.SetSync(key, key))
+			}
+
+			testCases := []struct {
+				prefix []byte
+				expect [][]byte
+			}{
+				{bz("a/"), [][]byte{bz("a/1"), bz("a/3")}},
+				{bz("c"), nil},
+				{[]byte{0xff}, [][]byte{{0xff}, {0xff, 0x01}, {0xff, 0xff}, {0xff, 0xff, 0x00}}},
+				{[]byte{0xff, 0xff}, [][]byte{{0xff, 0xff}, {0xff, 0xff, 0x00}}},
+			}
+			for _, tc := range testCases {
+				itr, err := IteratePrefix(db, tc.prefix)
+				require.NoError(t, err)
+				var keys [][]byte
+				for ; itr.Valid(); itr.Next() {
+					keys = append(keys, itr.Key())
+				}
+				require.NoError(t, itr.Close())
+				require.Equal(t, tc.expect, keys, "prefix %x", tc.prefix)
+
+				itr, err = ReverseIteratePrefix(db, tc.prefix)
+				require.NoError(t, err)
+				keys = nil
+				for ; itr.Valid(); itr.Next() {
+					keys = append([][]byte{itr.Key()}, keys...)
+				}
+				require.NoError(t, itr.Close())
+				require.Equal(t, tc.expect, keys, "reverse prefix %x", tc.prefix)
+			}
+
+			// Prefix iteration within a prefixed database.
+			pdb := NewPrefixDB(db, bz("a"))
+			itr, err := ReverseIteratePrefix(pdb, bz("/"))
+			require.NoError(t, err)
+			checkItem(t, itr, bz("/3"), bz("a/3"))
+			checkNext(t, itr, true)
+			checkItem(t, itr, bz("/1"), bz("a/1"))
+			checkNext(t, itr, false)
+			require.NoError(t, itr.Close())
+		})
+	}
+}
+
+func TestPrefixEndBytes(t *testing.T) {
+	testCases := []struct {
+		prefix []byte
+		expect []byte
+	}{
+		{nil, nil},
+		{[]byte{}, nil},
+		{[]byte{0x00}, []byte{0x01}},
+		{[]byte{0x01, 0xff}, []byte{0x02}},
+		{[]byte{0x01, 0xff, 0xff}, []byte{0x02}},
+		{[]byte{0x01, 0x02, 0xff}, []byte{0x01, 0x03}},
+		{[]byte{0xff}, nil},
+		{[]byte{0xff, 0xff}, nil},
+	}
+	for _, tc := range testCases {
+		prefix := cp(tc.prefix)
+		require.Equal(t, tc.expect, PrefixEndBytes(prefix), "prefix %x", tc.prefix)
+		require.True(t, bytes.Equal(tc.prefix, prefix), "prefix modified")
+	}
+}